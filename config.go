@@ -0,0 +1,208 @@
+package frodo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Config describes a parameter set for NewParameters, in JSON with the keys of the tags:
+//
+//	{"name": "FrodoKEM-640-SHAKE", "n": 640, "mbar": 8, "nbar": 8, "d": 15, "b": 2,
+//	 "errorTable": [4643, 13363, ..., 32767], "generator": "SHAKE128", "shake": "SHAKE128",
+//	 "seedABytes": 16, "secretBytes": 16, "seedSEBytes": 32, "saltBytes": 32}
+type Config struct {
+	Name        string   `json:"name"`        // name of the set, it must not be the name of a different registered set
+	N           int      `json:"n"`           // n ≡ 0 (mod 8), the dimension of A
+	MBar        int      `json:"mbar"`        // m̄, rows of S', E' and C1
+	NBar        int      `json:"nbar"`        // n̄, columns of S, E and B
+	D           int      `json:"d"`           // q = 2^D, D ≤ 16
+	B           int      `json:"b"`           // bits encoded in an entry, B ≤ D
+	ErrorTable  []uint16 `json:"errorTable"`  // cumulative distribution of χ, non-decreasing and ending at 2^15 - 1
+	Generator   string   `json:"generator"`   // generator of A, SHAKE128 or AES128
+	SHAKE       string   `json:"shake"`       // hash of seeds and keys, SHAKE128 or SHAKE256
+	SeedABytes  int      `json:"seedABytes"`  // byte length of seedA, 16 for AES128
+	SecretBytes int      `json:"secretBytes"` // byte length of s, k, pkh and ss
+	SeedSEBytes int      `json:"seedSEBytes"` // byte length of seedSE
+	SaltBytes   int      `json:"saltBytes"`   // byte length of the salt of ciphertexts, 0 for none
+}
+
+// generator names of Config
+var generators = map[string]int{"SHAKE128": genSHAKE128, "AES128": genAES128}
+
+// NewParameters returns the parameter set of c after checking its invariants,
+// the errors wrap ErrInvalidParameters and name the field at fault. The configuration of
+// a registered set returns that set, other sets run on the generic code of the package
+// and are not registered, see Lookup
+func NewParameters(c Config) (*Parameters, error) {
+
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	c.Generator, c.SHAKE = strings.ToUpper(c.Generator), strings.ToUpper(c.SHAKE)
+	if registered, err := Lookup(c.Name); err == nil {
+		if !reflect.DeepEqual(registered.Config(), c) {
+			return nil, fmt.Errorf("%w: name %q is the name of a different registered set", ErrInvalidParameters, c.Name)
+		}
+		return registered, nil
+	}
+
+	l := c.B * c.MBar * c.NBar / 8
+	return &Parameters{
+		name:     c.Name,
+		no:       c.N,
+		q:        uint16(uint32(1)<<uint(c.D) - 1),
+		d:        c.D,
+		m:        c.MBar,
+		n:        c.NBar,
+		b:        c.B,
+		l:        l,
+		lseedA:   c.SeedABytes,
+		lseedSE:  c.SeedSEBytes,
+		lens:     c.SecretBytes,
+		lenz:     16,
+		lenpkh:   c.SecretBytes,
+		lenk:     c.SecretBytes,
+		lenss:    c.SecretBytes,
+		lenX:     2,
+		x:        append([]uint16(nil), c.ErrorTable...),
+		lenM:     l,
+		lenSalt:  c.SaltBytes,
+		gen:      generators[c.Generator],
+		shake128: c.SHAKE == "SHAKE128",
+	}, nil
+}
+
+// check returns the first invariant of the parameter set c that does not hold
+func (c *Config) check() error {
+
+	invalid := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidParameters, fmt.Sprintf(format, a...))
+	}
+
+	switch {
+	case c.Name == "":
+		return invalid("name is empty")
+	case c.N <= 0 || c.N%8 != 0 || c.N > 1<<16:
+		return invalid("n must be a positive multiple of 8 of at most 2^16, got %d", c.N)
+	case c.MBar <= 0 || c.NBar <= 0:
+		return invalid("mbar and nbar must be positive, got %d and %d", c.MBar, c.NBar)
+	case c.D < 1 || c.D > 16:
+		return invalid("d must be in [1, 16], got %d", c.D)
+	case c.B < 1 || c.B > c.D:
+		return invalid("b must be in [1, d = %d], got %d", c.D, c.B)
+	case c.B*c.MBar*c.NBar%8 != 0:
+		return invalid("l = b·mbar·nbar = %d bits must be whole bytes", c.B*c.MBar*c.NBar)
+	case c.D*c.MBar*c.NBar%8 != 0:
+		return invalid("packed C2 of d·mbar·nbar = %d bits must be whole bytes", c.D*c.MBar*c.NBar)
+	}
+
+	X := c.ErrorTable
+	if len(X) == 0 || len(X) > 1<<uint(c.D-1) {
+		return invalid("errorTable must have 1 to 2^(d-1) = %d entries, got %d", 1<<uint(c.D-1), len(X))
+	}
+	for z := range X {
+		if X[z] > 1<<15-1 {
+			return invalid("errorTable entry %d = %d is not below 2^15", z, X[z])
+		}
+		if z > 0 && X[z] < X[z-1] {
+			return invalid("errorTable must be non-decreasing, entry %d = %d is below %d", z, X[z], X[z-1])
+		}
+	}
+	if X[len(X)-1] != 1<<15-1 {
+		return invalid("errorTable must end at 2^15 - 1, got %d", X[len(X)-1])
+	}
+
+	gen, ok := generators[strings.ToUpper(c.Generator)]
+	switch {
+	case !ok:
+		return invalid("generator must be SHAKE128 or AES128, got %q", c.Generator)
+	case !strings.EqualFold(c.SHAKE, "SHAKE128") && !strings.EqualFold(c.SHAKE, "SHAKE256"):
+		return invalid("shake must be SHAKE128 or SHAKE256, got %q", c.SHAKE)
+	case c.SeedABytes <= 0 || gen == genAES128 && c.SeedABytes != 16:
+		return invalid("seedABytes must be positive and 16 for AES128, got %d", c.SeedABytes)
+	case c.SecretBytes <= 0:
+		return invalid("secretBytes must be positive, got %d", c.SecretBytes)
+	case c.SeedSEBytes <= 0:
+		return invalid("seedSEBytes must be positive, got %d", c.SeedSEBytes)
+	case c.SaltBytes < 0:
+		return invalid("saltBytes must not be negative, got %d", c.SaltBytes)
+	}
+	return nil
+}
+
+// Config returns the description of the parameter set, NewParameters(param.Config())
+// returns an equivalent set. The generator of Legacy sets is "legacy", which NewParameters rejects
+func (param *Parameters) Config() Config {
+
+	c := Config{
+		Name:        param.name,
+		N:           param.no,
+		MBar:        param.m,
+		NBar:        param.n,
+		D:           param.d,
+		B:           param.b,
+		ErrorTable:  param.ErrorTable(),
+		Generator:   "legacy",
+		SHAKE:       "SHAKE256",
+		SeedABytes:  param.lseedA,
+		SecretBytes: param.lenss,
+		SeedSEBytes: param.lseedSE,
+		SaltBytes:   param.lenSalt,
+	}
+	for name, gen := range generators {
+		if gen == param.gen {
+			c.Generator = name
+		}
+	}
+	if param.shake128 {
+		c.SHAKE = "SHAKE128"
+	}
+	return c
+}
+
+// LoadParameters reads a JSON Config, or an array of them, from r and returns their parameter sets,
+// unknown keys are errors
+func LoadParameters(r io.Reader) ([]*Parameters, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("frodo: reading parameters: %w", err)
+	}
+
+	var configs []Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		err = dec.Decode(&configs)
+	} else {
+		configs = make([]Config, 1)
+		err = dec.Decode(&configs[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParameters, err)
+	}
+
+	sets := make([]*Parameters, len(configs))
+	for i, c := range configs {
+		if sets[i], err = NewParameters(c); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// LoadParametersFile returns the parameter sets of the JSON file name, see LoadParameters
+func LoadParametersFile(name string) ([]*Parameters, error) {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("frodo: reading parameters: %w", err)
+	}
+	defer f.Close()
+	return LoadParameters(f)
+}
@@ -0,0 +1,130 @@
+package frodo
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Encapsulator precomputes encapsulations to one public key in a background goroutine,
+// so that Encaps only takes a ready one from the pool.
+//
+// The whole encapsulation is precomputed, not only S'·A + E1 and S'·B + E2: in FrodoKEM
+// seedSE = SHAKE(pkh || m || salt) depends on the message m, and decapsulation recomputes
+// S', E1, E2 from it, so they cannot be drawn before m. Since m and salt are random coins of
+// the encapsulator, nothing is left for the online step. Every encapsulation is handed out
+// at most once, secrets left in the pool are wiped by Close
+type Encapsulator struct {
+	pk     *PreparedPublicKey
+	random io.Reader
+	pool   chan encapsulation
+	done   chan struct{}
+	close  sync.Once
+	err    error // why fill stopped, set before done is closed
+}
+
+// encapsulation is a ciphertext with its secret ss
+type encapsulation struct {
+	ct *EncapsCipherText
+	ss []byte
+}
+
+// NewEncapsulator validates pk and starts precomputing up to depth encapsulations to it
+// using crypto/rand, depth ≥ 1; Close stops the background goroutine
+func (param *Parameters) NewEncapsulator(pk *EncapsPublicKey, depth int) (*Encapsulator, error) {
+	return param.newEncapsulator(pk, depth, rand.Reader)
+}
+
+// newEncapsulator is NewEncapsulator drawing the coins of encapsulations from random
+func (param *Parameters) newEncapsulator(pk *EncapsPublicKey, depth int, random io.Reader) (*Encapsulator, error) {
+
+	prepared, err := param.PreparePublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	e := &Encapsulator{pk: prepared, random: random, pool: make(chan encapsulation, depth), done: make(chan struct{})}
+	go e.fill()
+	return e, nil
+}
+
+// fill precomputes encapsulations until the encapsulator is closed, then closes the pool.
+// If random fails, it keeps the error for Encaps and shuts the encapsulator down as Close does
+func (e *Encapsulator) fill() {
+
+	for {
+		ct, ss, err := e.pk.EncapsFrom(e.random)
+		if err != nil {
+			e.close.Do(func() {
+				e.err = fmt.Errorf("frodo: encapsulator stopped: %w", err)
+				close(e.done)
+			})
+			close(e.pool)
+			for t := range e.pool {
+				wipe(t.ss)
+			}
+			return
+		}
+		select {
+		case e.pool <- encapsulation{ct: ct, ss: ss}:
+		case <-e.done:
+			wipe(ss)
+			close(e.pool)
+			return
+		}
+	}
+}
+
+// Encaps returns a precomputed ciphertext and secret ss, it waits for one if the pool is empty;
+// it returns ErrClosed once the encapsulator is closed, or the wrapped error of the
+// randomness source once it failed
+func (e *Encapsulator) Encaps() (ct *EncapsCipherText, ss []byte, err error) {
+
+	select {
+	case <-e.done:
+		return nil, nil, e.closed()
+	default:
+	}
+	select {
+	case <-e.done:
+		return nil, nil, e.closed()
+	case t, ok := <-e.pool:
+		if !ok {
+			return nil, nil, e.closed()
+		}
+		return t.ct, t.ss, nil
+	}
+}
+
+// closed returns the error of Encaps once done is closed
+func (e *Encapsulator) closed() error {
+
+	if e.err != nil {
+		return e.err
+	}
+	return ErrClosed
+}
+
+// Len returns the number of encapsulations ready in the pool
+func (e *Encapsulator) Len() int {
+	return len(e.pool)
+}
+
+// Close stops the background goroutine and wipes the secrets of the pool, it is idempotent
+func (e *Encapsulator) Close() {
+
+	e.close.Do(func() { close(e.done) })
+	for t := range e.pool { // until fill closes the pool
+		wipe(t.ss)
+	}
+}
+
+// wipe overwrites b with zeros
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
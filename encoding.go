@@ -0,0 +1,191 @@
+package frodo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errUnboundKey is returned when a key or a ciphertext does not know its parameter set
+var errUnboundKey = errors.New("frodo: parameter set is unknown, use the Unmarshal methods of Parameters")
+
+// PublicKeySize returns the byte length of the packed public key seedA || b
+func (param *Parameters) PublicKeySize() int {
+	return param.lseedA + param.d*param.no*param.n/8
+}
+
+// PrivateKeySize returns the byte length of the packed secret key s || seedA || b || Sᵀ || pkh
+func (param *Parameters) PrivateKeySize() int {
+	return param.lens + param.PublicKeySize() + 2*param.no*param.n + param.lenpkh
+}
+
+// CiphertextSize returns the byte length of the packed ciphertext c1 || c2 || salt
+func (param *Parameters) CiphertextSize() int {
+	return param.d*param.m*param.no/8 + param.d*param.m*param.n/8 + param.lenSalt
+}
+
+// MarshalBinary returns the public key encoded as seedA || b
+func (pk *EncapsPublicKey) MarshalBinary() ([]byte, error) {
+
+	var b []byte
+	b = append(b, pk.SeedA...)
+	b = append(b, pk.B...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes seedA || b into the public key, pk must be bound to
+// a parameter set, like keys returned by EncapsKeyGen or UnmarshalEncapsPublicKey
+func (pk *EncapsPublicKey) UnmarshalBinary(data []byte) error {
+
+	if pk.param == nil {
+		return errUnboundKey
+	}
+	key, err := pk.param.UnmarshalEncapsPublicKey(data)
+	if err != nil {
+		return err
+	}
+	*pk = *key
+	return nil
+}
+
+// MarshalBinary returns the secret key encoded as s || seedA || b || Sᵀ || pkh,
+// the entries of Sᵀ are 16-bit little-endian
+func (sk *EncapsSecretKey) MarshalBinary() ([]byte, error) {
+
+	if sk.param == nil {
+		return nil, errUnboundKey
+	}
+
+	var b []byte
+	b = append(b, sk.SeedS...)
+	b = append(b, sk.SeedA...)
+	b = append(b, sk.B...)
+	for k := 0; k < sk.param.n; k++ {
+		for j := 0; j < sk.param.no; j++ {
+			e := sk.param.signExtend(sk.S[j][k])
+			b = append(b, byte(e), byte(e>>8))
+		}
+	}
+	b = append(b, sk.Pkh...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes s || seedA || b || Sᵀ || pkh into the secret key, sk must be
+// bound to a parameter set, like keys returned by EncapsKeyGen or UnmarshalEncapsSecretKey
+func (sk *EncapsSecretKey) UnmarshalBinary(data []byte) error {
+
+	if sk.param == nil {
+		return errUnboundKey
+	}
+	key, err := sk.param.UnmarshalEncapsSecretKey(data)
+	if err != nil {
+		return err
+	}
+	*sk = *key
+	return nil
+}
+
+// MarshalSeed returns the master seed of a key created by DeriveKeyPair,
+// it is a compact form of the secret key regenerated by UnmarshalEncapsSecretKeySeed
+func (sk *EncapsSecretKey) MarshalSeed() ([]byte, error) {
+
+	if sk.seed == nil {
+		return nil, fmt.Errorf("%w: key was not derived from a seed", ErrInvalidSecretKey)
+	}
+	return append([]byte(nil), sk.seed...), nil
+}
+
+// MarshalBinary returns the ciphertext encoded as c1 || c2 || salt
+func (ct *EncapsCipherText) MarshalBinary() ([]byte, error) {
+
+	var b []byte
+	b = append(b, ct.C1...)
+	b = append(b, ct.C2...)
+	b = append(b, ct.Salt...)
+	return b, nil
+}
+
+// UnmarshalBinary decodes c1 || c2 || salt into the ciphertext, ct must be bound to
+// a parameter set, like ciphertexts returned by Encaps or UnmarshalEncapsCipherText
+func (ct *EncapsCipherText) UnmarshalBinary(data []byte) error {
+
+	if ct.param == nil {
+		return errUnboundKey
+	}
+	c, err := ct.param.UnmarshalEncapsCipherText(data)
+	if err != nil {
+		return err
+	}
+	*ct = *c
+	return nil
+}
+
+// UnmarshalEncapsPublicKey decodes the public key seedA || b of PublicKeySize bytes
+func (param *Parameters) UnmarshalEncapsPublicKey(data []byte) (*EncapsPublicKey, error) {
+
+	if len(data) != param.PublicKeySize() {
+		return nil, fmt.Errorf("%w: %s public key must be %d bytes, got %d", ErrInvalidPublicKey, param.name, param.PublicKeySize(), len(data))
+	}
+
+	pk := &EncapsPublicKey{param: param}
+	pk.SeedA = append([]byte(nil), data[:param.lseedA]...)
+	pk.B = append([]byte(nil), data[param.lseedA:]...)
+	return pk, nil
+}
+
+// UnmarshalEncapsSecretKey decodes the secret key s || seedA || b || Sᵀ || pkh of PrivateKeySize bytes
+func (param *Parameters) UnmarshalEncapsSecretKey(data []byte) (*EncapsSecretKey, error) {
+
+	if len(data) != param.PrivateKeySize() {
+		return nil, fmt.Errorf("%w: %s secret key must be %d bytes, got %d", ErrInvalidSecretKey, param.name, param.PrivateKeySize(), len(data))
+	}
+
+	sk, pkLen := &EncapsSecretKey{param: param}, param.PublicKeySize()
+	sk.SeedS = append([]byte(nil), data[:param.lens]...)
+	data = data[param.lens:]
+	sk.SeedA = append([]byte(nil), data[:param.lseedA]...)
+	sk.B = append([]byte(nil), data[param.lseedA:pkLen]...)
+	data = data[pkLen:]
+
+	sk.S = make([][]uint16, param.no)
+	for j := range sk.S {
+		sk.S[j] = make([]uint16, param.n)
+		for k := range sk.S[j] {
+			index := 2 * (k*param.no + j)
+			sk.S[j][k] = (uint16(data[index]) | uint16(data[index+1])<<8) & param.q
+		}
+	}
+	sk.Pkh = append([]byte(nil), data[2*param.no*param.n:]...)
+	if err := param.checkSmall(ErrInvalidSecretKey, "S", sk.S); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// UnmarshalEncapsSecretKeySeed regenerates the secret key S, b and pkh from
+// the master seed returned by MarshalSeed, see DeriveKeyPair
+func (param *Parameters) UnmarshalEncapsSecretKeySeed(seed []byte) (*EncapsSecretKey, error) {
+
+	_, sk, err := param.DeriveKeyPair(seed)
+	return sk, err
+}
+
+// UnmarshalEncapsCipherText decodes the ciphertext c1 || c2 || salt of CiphertextSize bytes
+func (param *Parameters) UnmarshalEncapsCipherText(data []byte) (*EncapsCipherText, error) {
+
+	if len(data) != param.CiphertextSize() {
+		return nil, fmt.Errorf("%w: %s ciphertext must be %d bytes, got %d", ErrInvalidCiphertext, param.name, param.CiphertextSize(), len(data))
+	}
+
+	ct, c1Len, c2Len := &EncapsCipherText{param: param}, param.d*param.m*param.no/8, param.d*param.m*param.n/8
+	ct.C1 = append([]byte(nil), data[:c1Len]...)
+	ct.C2 = append([]byte(nil), data[c1Len:c1Len+c2Len]...)
+	ct.Salt = append([]byte(nil), data[c1Len+c2Len:]...)
+	return ct, nil
+}
+
+// signExtend returns the 16-bit two's complement of the small element e є Zq,
+// the sign bit D-1 is spread over the bits above q with a mask and not a branch
+func (param *Parameters) signExtend(e uint16) uint16 {
+
+	return e | -((e>>uint(param.d-1))&1) & ^param.q
+}
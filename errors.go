@@ -0,0 +1,180 @@
+package frodo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the checked operations, they are wrapped with details
+// and should be tested with errors.Is
+var (
+	ErrInvalidPublicKey  = errors.New("frodo: invalid public key")
+	ErrInvalidSecretKey  = errors.New("frodo: invalid secret key")
+	ErrInvalidCiphertext = errors.New("frodo: invalid ciphertext")
+	ErrInvalidMessage    = errors.New("frodo: invalid message")
+	ErrParameterMismatch = errors.New("frodo: parameter set mismatch")
+	ErrClosed            = errors.New("frodo: encapsulator is closed")
+	ErrUnknownParameters = errors.New("frodo: unknown parameter set")
+	ErrInvalidParameters = errors.New("frodo: invalid parameter set")
+)
+
+// checkSet returns ErrParameterMismatch if other is a different parameter set,
+// unbound keys (other == nil) are checked by their lengths only
+func (param *Parameters) checkSet(other *Parameters) error {
+
+	if other != nil && (other.name != param.name || other.gen != param.gen) {
+		return fmt.Errorf("%w: %s used with %s", ErrParameterMismatch, other.name, param.name)
+	}
+	return nil
+}
+
+// checkLen returns err wrapped with details if len(b) != length
+func checkLen(err error, field string, b []byte, length int) error {
+
+	if len(b) != length {
+		return fmt.Errorf("%w: %s must be %d bytes, got %d", err, field, length, len(b))
+	}
+	return nil
+}
+
+// checkMatrix returns err wrapped with details if A is not an n1-by-n2 matrix over Zq
+func (param *Parameters) checkMatrix(err error, field string, A [][]uint16, n1, n2 int) error {
+
+	if len(A) != n1 {
+		return fmt.Errorf("%w: %s must have %d rows, got %d", err, field, n1, len(A))
+	}
+	for i := range A {
+		if len(A[i]) != n2 {
+			return fmt.Errorf("%w: %s must have %d columns, got %d in row %d", err, field, n2, len(A[i]), i)
+		}
+		for j := range A[i] {
+			if A[i][j] > param.q {
+				return fmt.Errorf("%w: %s entry (%d, %d) is not in Zq", err, field, i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSmall returns err wrapped with details if an entry of S is out of the support of χ,
+// it runs in constant time on the entries of S
+func (param *Parameters) checkSmall(err error, field string, S [][]uint16) error {
+
+	bound, bad := uint16(len(param.x)-1), uint16(0)
+	for i := range S {
+		for j := range S[i] {
+			e := param.signExtend(S[i][j])
+			abs := e ^ -(e >> 15) + (e >> 15) // |e| as 16-bit two's complement
+			bad |= (bound - abs) >> 15        // 1 if abs > bound
+		}
+	}
+	if bad != 0 {
+		return fmt.Errorf("%w: %s entries are out of the error distribution", err, field)
+	}
+	return nil
+}
+
+// ValidateEncapsPublicKey checks the parameter set and the lengths of pk
+func (param *Parameters) ValidateEncapsPublicKey(pk *EncapsPublicKey) error {
+
+	if pk == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidPublicKey)
+	}
+	if err := param.checkSet(pk.param); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidPublicKey, "seedA", pk.SeedA, param.lseedA); err != nil {
+		return err
+	}
+	return checkLen(ErrInvalidPublicKey, "b", pk.B, param.d*param.no*param.n/8)
+}
+
+// ValidateEncapsSecretKey checks the parameter set, the lengths of sk and the entries of S
+func (param *Parameters) ValidateEncapsSecretKey(sk *EncapsSecretKey) error {
+
+	if sk == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidSecretKey)
+	}
+	if err := param.checkSet(sk.param); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidSecretKey, "s", sk.SeedS, param.lens); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidSecretKey, "seedA", sk.SeedA, param.lseedA); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidSecretKey, "b", sk.B, param.d*param.no*param.n/8); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidSecretKey, "pkh", sk.Pkh, param.lenpkh); err != nil {
+		return err
+	}
+	if err := param.checkMatrix(ErrInvalidSecretKey, "S", sk.S, param.no, param.n); err != nil {
+		return err
+	}
+	return param.checkSmall(ErrInvalidSecretKey, "S", sk.S)
+}
+
+// ValidateEncapsCipherText checks the parameter set and the lengths of ct
+func (param *Parameters) ValidateEncapsCipherText(ct *EncapsCipherText) error {
+
+	if ct == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidCiphertext)
+	}
+	if err := param.checkSet(ct.param); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidCiphertext, "c1", ct.C1, param.d*param.m*param.no/8); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidCiphertext, "c2", ct.C2, param.d*param.m*param.n/8); err != nil {
+		return err
+	}
+	return checkLen(ErrInvalidCiphertext, "salt", ct.Salt, param.lenSalt)
+}
+
+// ValidatePublicKey checks the parameter set, the lengths of seedA and the matrix B є Zq of pk
+func (param *Parameters) ValidatePublicKey(pk *PublicKey) error {
+
+	if pk == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidPublicKey)
+	}
+	if err := param.checkSet(pk.param); err != nil {
+		return err
+	}
+	if err := checkLen(ErrInvalidPublicKey, "seedA", pk.SeedA, param.lseedA); err != nil {
+		return err
+	}
+	return param.checkMatrix(ErrInvalidPublicKey, "B", pk.B, param.no, param.n)
+}
+
+// ValidateSecretKey checks the parameter set and the matrix S of sk
+func (param *Parameters) ValidateSecretKey(sk *SecretKey) error {
+
+	if sk == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidSecretKey)
+	}
+	if err := param.checkSet(sk.param); err != nil {
+		return err
+	}
+	if err := param.checkMatrix(ErrInvalidSecretKey, "S", sk.S, param.no, param.n); err != nil {
+		return err
+	}
+	return param.checkSmall(ErrInvalidSecretKey, "S", sk.S)
+}
+
+// ValidateCipherText checks the parameter set and the matrices C1, C2 є Zq of cipher
+func (param *Parameters) ValidateCipherText(cipher *CipherText) error {
+
+	if cipher == nil {
+		return fmt.Errorf("%w: nil", ErrInvalidCiphertext)
+	}
+	if err := param.checkSet(cipher.param); err != nil {
+		return err
+	}
+	if err := param.checkMatrix(ErrInvalidCiphertext, "C1", cipher.C1, param.m, param.no); err != nil {
+		return err
+	}
+	return param.checkMatrix(ErrInvalidCiphertext, "C2", cipher.C2, param.m, param.n)
+}
@@ -6,18 +6,22 @@ type Frodo interface {
 	Decode(K [][]uint16) []byte                   // Decode decodes the m-by-n matrix K into a bit string of length l = B·m·n. dc(c) = ⌊c·2^B/q⌉ mod 2^B
 	Pack(C [][]uint16) []byte                     // Pack packs a matrix into a bit string
 	Unpack(b []byte, n1, n2 int) [][]uint16       // Unpack unpacks a bit string into a matrix n1-by-n2
-	Gen(seed []byte) [][]uint16                   // Gen returns a pseudorandom matrix using SHAKE128
+	Gen(seed []byte) [][]uint16                   // Gen returns a pseudorandom matrix using SHAKE128 or AES128
 	Sample(t uint16) uint16                       // Sample returns a sample e from the distribution χ
 	SampleMatrix(r []byte, n1, n2 int) [][]uint16 // SampleMatrix sample the n1-by-n2 matrix entry
 }
 
-// Parameters of frodo KEM mechanism
+// Parameters of frodo KEM mechanism. A parameter set is immutable: the constructors
+// return values shared by every caller, Legacy and WithConcurrency return new sets, and
+// the fields are only read through the accessors. All methods are safe for concurrent use,
+// the values they return (keys, ciphertexts, Scratch) are owned by the caller
 type Parameters struct {
+	name    string   		// name of the parameter set in the specification
 	no      int      		// n ≡ 0 (mod 8) the main parameter
 	q       uint16   		// a power-of-two integer modulus with exponent D ≤ 16 !! minus one for bit masking
-	D       int      		// a power 
+	d       int      		// a power
 	m, n    int      		// integer matrix dimensions with
-	B       int      		// the number of bits encoded in each matrix entry
+	b       int      		// the number of bits encoded in each matrix entry
 	l       int      		// B·m·n, the length of bit strings that are encoded as m-by-n matrices
 	lseedA  int      		// the byte length of seed used for pseudorandom pk-matrix generation
 	lseedSE int      		// the byte length of seed used for pseudorandom bit generation for error sampling
@@ -25,19 +29,53 @@ type Parameters struct {
 	lenk    int      		// the byte length of seed used for generating seedSE in Encaps (KEM)
 	lenss   int      		// the byte length of secret ss (KEM)
 	lenX    int      		// the byte length of χ distribution
-	X       []uint16 		// a probability distribution on Z, rounded Gaussian distribution
+	x       []uint16 		// a probability distribution on Z, rounded Gaussian distribution
 	lenM    int      		// byte length of message
+	lenSalt int      		// byte length of salt of ciphertexts (KEM), 0 for the 2019 and ephemeral sets
+	gen     int      		// generator of the pseudorandom matrix A
+	workers int      		// goroutines sharing the rows of A, 1 or less is sequential
+	kern    *kernels 		// kernels specialized for the set, nil for the generic code
+	shake128 bool    		// SHAKE128 instead of SHAKE256 hashes seeds and keys, only for n = 640 in [FKEM]
 }
 
-// Frodo640 returns Parameters struct no.640
+// the parameter sets are built once and shared by every caller, they are never modified
+var (
+	frodo640           = newFrodo640()
+	frodo976           = newFrodo976()
+	frodo1344          = newFrodo1344()
+	frodo640AES        = newFrodo640AES()
+	frodo976AES        = newFrodo976AES()
+	frodo1344AES       = newFrodo1344AES()
+	frodoKEM640SHAKE   = newFrodoKEM640SHAKE()
+	frodoKEM976SHAKE   = newFrodoKEM976SHAKE()
+	frodoKEM1344SHAKE  = newFrodoKEM1344SHAKE()
+	frodoKEM640AES     = newFrodoKEM640AES()
+	frodoKEM976AES     = newFrodoKEM976AES()
+	frodoKEM1344AES    = newFrodoKEM1344AES()
+	eFrodoKEM640SHAKE  = newEFrodoKEM640SHAKE()
+	eFrodoKEM976SHAKE  = newEFrodoKEM976SHAKE()
+	eFrodoKEM1344SHAKE = newEFrodoKEM1344SHAKE()
+	eFrodoKEM640AES    = newEFrodoKEM640AES()
+	eFrodoKEM976AES    = newEFrodoKEM976AES()
+	eFrodoKEM1344AES   = newEFrodoKEM1344AES()
+)
+
+// Frodo640 returns Parameters struct no.640 of the 2019 specification [FKEM],
+// the matrix A is generated using SHAKE128. Every call returns the same immutable set
 func Frodo640() *Parameters {
+	return frodo640
+}
+
+// newFrodo640 builds the set returned by Frodo640
+func newFrodo640() *Parameters {
 
 	param := new(Parameters)
 
+	param.name = "FrodoKEM-640-SHAKE-2019"
 	param.no = 640
 	param.q = 0x7fff
-	param.D = 15
-	param.B = 2
+	param.d = 15
+	param.b = 2
 	param.m = 8
 	param.n = 8
 	param.lseedA = 16 
@@ -50,20 +88,29 @@ func Frodo640() *Parameters {
 	param.lenss = 16
 	param.lenX = 2
 	param.l = 16
-	param.X = []uint16{4643, 13363, 20579, 25843, 29227, 31145, 32103, 32525, 32689, 32745, 32762, 32766, 32767}
+	param.x = []uint16{4643, 13363, 20579, 25843, 29227, 31145, 32103, 32525, 32689, 32745, 32762, 32766, 32767}
+	param.kern = &kernels640
+	param.shake128 = true
 
 	return param
 }
 
-// Frodo976 returns Parameters struct no.976
+// Frodo976 returns Parameters struct no.976 of the 2019 specification [FKEM],
+// the matrix A is generated using SHAKE128
 func Frodo976() *Parameters {
+	return frodo976
+}
+
+// newFrodo976 builds the set returned by Frodo976
+func newFrodo976() *Parameters {
 
 	param := new(Parameters)
 
+	param.name = "FrodoKEM-976-SHAKE-2019"
 	param.no = 976
 	param.q = 0xffff
-	param.D = 16
-	param.B = 3
+	param.d = 16
+	param.b = 3
 	param.m = 8
 	param.n = 8
 	param.lseedA = 16
@@ -71,25 +118,33 @@ func Frodo976() *Parameters {
 	param.lenM = 24
 	param.lens = 24
 	param.lenk = 24
-	param.lenz = 24
+	param.lenz = 16
 	param.lenpkh = 24
 	param.lenss = 24
 	param.lenX = 2
 	param.l = 24
-	param.X = []uint16{5638, 15915, 23689, 28571, 31116, 32217, 32613, 32731, 32760, 32766, 32767}
+	param.x = []uint16{5638, 15915, 23689, 28571, 31116, 32217, 32613, 32731, 32760, 32766, 32767}
+	param.kern = &kernels976
 
 	return param
 }
 
-// Frodo1344 returns Parameters struct no.1344
+// Frodo1344 returns Parameters struct no.1344 of the 2019 specification [FKEM],
+// the matrix A is generated using SHAKE128
 func Frodo1344() *Parameters {
+	return frodo1344
+}
+
+// newFrodo1344 builds the set returned by Frodo1344
+func newFrodo1344() *Parameters {
 
 	param := new(Parameters)
 
+	param.name = "FrodoKEM-1344-SHAKE-2019"
 	param.no = 1344
 	param.q = 0xffff
-	param.D = 16
-	param.B = 4
+	param.d = 16
+	param.b = 4
 	param.m = 8
 	param.n = 8
 	param.lseedA = 16
@@ -97,135 +152,590 @@ func Frodo1344() *Parameters {
 	param.lenM = 32
 	param.lens = 32
 	param.lenk = 32
-	param.lenz = 32
+	param.lenz = 16
 	param.lenpkh = 32
 	param.lenss = 32
 	param.lenX = 2
 	param.l = 32
-	param.X = []uint16{9142, 23462, 30338, 32361, 32725, 32765, 32767}
+	param.x = []uint16{9142, 23462, 30338, 32361, 32725, 32765, 32767}
+	param.kern = &kernels1344
+
+	return param
+}
+
+// Frodo640AES returns Parameters struct no.640 of the 2019 specification [FKEM],
+// the matrix A is generated using AES128
+func Frodo640AES() *Parameters {
+	return frodo640AES
+}
+
+// newFrodo640AES builds the set returned by Frodo640AES
+func newFrodo640AES() *Parameters {
+
+	param := newFrodo640()
+	param.name = "FrodoKEM-640-AES-2019"
+	param.gen = genAES128
+
+	return param
+}
+
+// Frodo976AES returns Parameters struct no.976 of the 2019 specification [FKEM],
+// the matrix A is generated using AES128
+func Frodo976AES() *Parameters {
+	return frodo976AES
+}
+
+// newFrodo976AES builds the set returned by Frodo976AES
+func newFrodo976AES() *Parameters {
+
+	param := newFrodo976()
+	param.name = "FrodoKEM-976-AES-2019"
+	param.gen = genAES128
+
+	return param
+}
+
+// Frodo1344AES returns Parameters struct no.1344 of the 2019 specification [FKEM],
+// the matrix A is generated using AES128
+func Frodo1344AES() *Parameters {
+	return frodo1344AES
+}
+
+// newFrodo1344AES builds the set returned by Frodo1344AES
+func newFrodo1344AES() *Parameters {
+
+	param := newFrodo1344()
+	param.name = "FrodoKEM-1344-AES-2019"
+	param.gen = genAES128
 
 	return param
 }
 
+// FrodoKEM640SHAKE returns FrodoKEM-640-SHAKE of the ISO standardization proposal [FISO]
+func FrodoKEM640SHAKE() *Parameters {
+	return frodoKEM640SHAKE
+}
+
+// newFrodoKEM640SHAKE builds the set returned by FrodoKEM640SHAKE
+func newFrodoKEM640SHAKE() *Parameters {
+	return iso(newFrodo640(), "FrodoKEM-640-SHAKE", false)
+}
+
+// FrodoKEM976SHAKE returns FrodoKEM-976-SHAKE of the ISO standardization proposal [FISO]
+func FrodoKEM976SHAKE() *Parameters {
+	return frodoKEM976SHAKE
+}
+
+// newFrodoKEM976SHAKE builds the set returned by FrodoKEM976SHAKE
+func newFrodoKEM976SHAKE() *Parameters {
+	return iso(newFrodo976(), "FrodoKEM-976-SHAKE", false)
+}
+
+// FrodoKEM1344SHAKE returns FrodoKEM-1344-SHAKE of the ISO standardization proposal [FISO]
+func FrodoKEM1344SHAKE() *Parameters {
+	return frodoKEM1344SHAKE
+}
+
+// newFrodoKEM1344SHAKE builds the set returned by FrodoKEM1344SHAKE
+func newFrodoKEM1344SHAKE() *Parameters {
+	return iso(newFrodo1344(), "FrodoKEM-1344-SHAKE", false)
+}
+
+// FrodoKEM640AES returns FrodoKEM-640-AES of the ISO standardization proposal [FISO]
+func FrodoKEM640AES() *Parameters {
+	return frodoKEM640AES
+}
+
+// newFrodoKEM640AES builds the set returned by FrodoKEM640AES
+func newFrodoKEM640AES() *Parameters {
+	return iso(newFrodo640AES(), "FrodoKEM-640-AES", false)
+}
+
+// FrodoKEM976AES returns FrodoKEM-976-AES of the ISO standardization proposal [FISO]
+func FrodoKEM976AES() *Parameters {
+	return frodoKEM976AES
+}
+
+// newFrodoKEM976AES builds the set returned by FrodoKEM976AES
+func newFrodoKEM976AES() *Parameters {
+	return iso(newFrodo976AES(), "FrodoKEM-976-AES", false)
+}
+
+// FrodoKEM1344AES returns FrodoKEM-1344-AES of the ISO standardization proposal [FISO]
+func FrodoKEM1344AES() *Parameters {
+	return frodoKEM1344AES
+}
+
+// newFrodoKEM1344AES builds the set returned by FrodoKEM1344AES
+func newFrodoKEM1344AES() *Parameters {
+	return iso(newFrodo1344AES(), "FrodoKEM-1344-AES", false)
+}
+
+// EFrodoKEM640SHAKE returns eFrodoKEM-640-SHAKE of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM640SHAKE() *Parameters {
+	return eFrodoKEM640SHAKE
+}
+
+// newEFrodoKEM640SHAKE builds the set returned by EFrodoKEM640SHAKE
+func newEFrodoKEM640SHAKE() *Parameters {
+	return iso(newFrodo640(), "eFrodoKEM-640-SHAKE", true)
+}
+
+// EFrodoKEM976SHAKE returns eFrodoKEM-976-SHAKE of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM976SHAKE() *Parameters {
+	return eFrodoKEM976SHAKE
+}
+
+// newEFrodoKEM976SHAKE builds the set returned by EFrodoKEM976SHAKE
+func newEFrodoKEM976SHAKE() *Parameters {
+	return iso(newFrodo976(), "eFrodoKEM-976-SHAKE", true)
+}
+
+// EFrodoKEM1344SHAKE returns eFrodoKEM-1344-SHAKE of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM1344SHAKE() *Parameters {
+	return eFrodoKEM1344SHAKE
+}
+
+// newEFrodoKEM1344SHAKE builds the set returned by EFrodoKEM1344SHAKE
+func newEFrodoKEM1344SHAKE() *Parameters {
+	return iso(newFrodo1344(), "eFrodoKEM-1344-SHAKE", true)
+}
+
+// EFrodoKEM640AES returns eFrodoKEM-640-AES of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM640AES() *Parameters {
+	return eFrodoKEM640AES
+}
+
+// newEFrodoKEM640AES builds the set returned by EFrodoKEM640AES
+func newEFrodoKEM640AES() *Parameters {
+	return iso(newFrodo640AES(), "eFrodoKEM-640-AES", true)
+}
+
+// EFrodoKEM976AES returns eFrodoKEM-976-AES of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM976AES() *Parameters {
+	return eFrodoKEM976AES
+}
+
+// newEFrodoKEM976AES builds the set returned by EFrodoKEM976AES
+func newEFrodoKEM976AES() *Parameters {
+	return iso(newFrodo976AES(), "eFrodoKEM-976-AES", true)
+}
+
+// EFrodoKEM1344AES returns eFrodoKEM-1344-AES of the ISO standardization proposal [FISO],
+// it is secure only if a public key is used for a single encapsulation
+func EFrodoKEM1344AES() *Parameters {
+	return eFrodoKEM1344AES
+}
+
+// newEFrodoKEM1344AES builds the set returned by EFrodoKEM1344AES
+func newEFrodoKEM1344AES() *Parameters {
+	return iso(newFrodo1344AES(), "eFrodoKEM-1344-AES", true)
+}
+
+// iso turns the 2019 parameter set param into the ISO one: seedSE is twice as long,
+// ciphertexts of the non-ephemeral sets carry a salt of the same length which is hashed
+// into seedSE || k and ss
+func iso(param *Parameters, name string, ephemeral bool) *Parameters {
+
+	param.name = name
+	param.lseedSE = 2 * param.lenss
+	if !ephemeral {
+		param.lenSalt = 2 * param.lenss
+	}
+
+	return param
+}
+
+// Name returns the name of the parameter set, for example FrodoKEM-640-AES
+func (param *Parameters) Name() string {
+	return param.name
+}
+
+// Legacy returns a copy of parameters that works like releases before the reference-compatible
+// encoding: A is generated with the earlier derivation, noise is sampled from big-endian words,
+// S of key pairs is sampled row by row instead of Sᵀ, messages are encoded most significant bit
+// first and z has lens bytes. It is kept only to use keys and ciphertexts created with those
+// releases; new keys should not be created with it
+func (param *Parameters) Legacy() *Parameters {
+
+	legacy := *param
+	legacy.name += "-legacy"
+	legacy.gen = genLegacy
+	legacy.lenz = legacy.lens
+	return &legacy
+}
+
+// WithConcurrency returns a copy of the parameter set which splits the generation of A
+// and the products A·S and S'·A across n goroutines, results are bit-identical to the
+// sequential path. n ≤ 1 is sequential, the default of every parameter set
+func (param *Parameters) WithConcurrency(n int) *Parameters {
+
+	parallel := *param
+	parallel.workers = n
+	return &parallel
+}
+
+// Concurrency returns the number of goroutines used for A, see WithConcurrency
+func (param *Parameters) Concurrency() int {
+
+	if param.workers < 1 {
+		return 1
+	}
+	return param.workers
+}
+
+// Dimension returns n, the dimension of the matrix A
+func (param *Parameters) Dimension() int {
+	return param.no
+}
+
+// MBar returns m̄, the number of rows of the matrices S', E' and C1
+func (param *Parameters) MBar() int {
+	return param.m
+}
+
+// NBar returns n̄, the number of columns of the matrices S, E and B
+func (param *Parameters) NBar() int {
+	return param.n
+}
+
+// ModulusBits returns D, the modulus is q = 2^D
+func (param *Parameters) ModulusBits() int {
+	return param.d
+}
+
+// ExtractedBits returns B, the number of bits encoded in every entry of an m̄-by-n̄ matrix
+func (param *Parameters) ExtractedBits() int {
+	return param.b
+}
+
+// ErrorTable returns a copy of the table of the cumulative distribution of χ
+// used by Sample, its last entry is 2^15 - 1
+func (param *Parameters) ErrorTable() []uint16 {
+	return append([]uint16(nil), param.x...)
+}
+
 // Encode encodes an integer 0 ≤ k < 2^B as an element in Zq 
 // by multiplying it by q/2B = 2^(D−B): ec(k) := k·q/2^B
 func (param *Parameters) Encode(k []byte) [][]uint16 {
+	return param.encode(k).slices()
+}
 
-	K := make([][]uint16, param.m)
-	for i := range K {
-		K[i] = make([]uint16, param.n)
-		for j := range K[i] {
-			temp := uint16(0)
-			for l := 0; l < param.B; l++ {
-				index, shift := ((i*param.n+j)*param.B+l)/8, uint(((i*param.n+j)*param.B+l)&7)
-				if k[index]&(byte(0x80)>>shift) != 0 { // litte-endian
-					temp |= uint16(1 << uint(l))
-				}
-			}
-			K[i][j] = param.ec(temp)
-		}
+// EncodeTo encodes k into the m-by-n matrix dst and returns dst, see Encode.
+// The rows are one bit string, a row may start inside a byte of k
+func (param *Parameters) EncodeTo(dst [][]uint16, k []byte) [][]uint16 {
+
+	var acc uint64
+	bits := 0
+	for i := range dst {
+		k, acc, bits = param.encodeBits(dst[i], k, acc, bits)
 	}
+	return dst
+}
+
+func (param *Parameters) encode(k []byte) *matrix {
+
+	K := newMatrix(param.m, param.n)
+	param.encodeEntries(K.data, k)
 	return K
 }
 
+// encodeEntries encodes the B·len(c) bits of k into the entries c, bits are little-endian:
+// bytes of k are shifted into a 64-bit accumulator and B bits are taken at a time
+func (param *Parameters) encodeEntries(c []uint16, k []byte) {
+	param.encodeBits(c, k, 0, 0)
+}
+
+// encodeBits is encodeEntries continuing a bit string, the low bits of acc are not taken yet;
+// it returns the rest of k and the bits left for the next entries
+func (param *Parameters) encodeBits(c []uint16, k []byte, acc uint64, bits int) ([]byte, uint64, int) {
+
+	j, mask := 0, uint64(1)<<uint(param.b)-1
+	for i := range c {
+		for bits < param.b {
+			acc |= uint64(param.bitOrder(k[j])) << uint(bits)
+			j, bits = j+1, bits+8
+		}
+		c[i] = param.ec(uint16(acc & mask))
+		acc, bits = acc>>uint(param.b), bits-param.b
+	}
+	return k[j:], acc, bits
+}
+
 // Decode decodes the m-by-n matrix K into a bit string of {0,1}^(B·m·n). dc(c) = ⌊c·2^B/q⌉ mod 2^B
 func (param *Parameters) Decode(K [][]uint16) []byte {
+	return param.DecodeTo(make([]byte, 0, param.l), K)
+}
 
-	k := make([]byte, param.l)
-	for i, row := range K {
-		for j := range row {
-			temp := param.dc(K[i][j])
-			for l := 0; l < param.B; l++ {
-				if temp&uint16(1<<uint(l)) != 0 {
-					index, shift := ((i*param.n+j)*param.B+l)/8, uint(((i*param.n+j)*param.B+l)&7)
-					k[index] |= byte(0x80) >> shift // litte-endian
-				}
-			}
+// DecodeTo appends the decoding of the m-by-n matrix K to dst and returns the extended slice, see Decode.
+// The rows are one bit string, a row may end inside a byte
+func (param *Parameters) DecodeTo(dst []byte, K [][]uint16) []byte {
+
+	var acc uint64
+	bits := 0
+	for i := range K {
+		dst, acc, bits = param.decodeBits(dst, K[i], acc, bits)
+	}
+	if bits > 0 {
+		dst = append(dst, param.bitOrder(byte(acc)))
+	}
+	return dst
+}
+
+func (param *Parameters) decode(K *matrix) []byte {
+	return param.decodeEntries(make([]byte, 0, param.l), K.data)
+}
+
+// decodeEntries appends the B·len(c) bits decoded from the entries c to dst, bits are little-endian:
+// B bits at a time are shifted into a 64-bit accumulator and taken out a byte at a time
+func (param *Parameters) decodeEntries(dst []byte, c []uint16) []byte {
+
+	dst, _, _ = param.decodeBits(dst, c, 0, 0)
+	return dst
+}
+
+// decodeBits is decodeEntries continuing a bit string, the low bits of acc are not appended yet;
+// it returns the bits left for the next entries
+func (param *Parameters) decodeBits(dst []byte, c []uint16, acc uint64, bits int) ([]byte, uint64, int) {
+
+	k := grow(&dst, (bits+param.b*len(c))/8)
+	j := 0
+	for i := range c {
+		acc |= uint64(param.dc(c[i])) << uint(bits)
+		for bits += param.b; bits >= 8; bits -= 8 {
+			k[j], acc, j = param.bitOrder(byte(acc)), acc>>8, j+1
 		}
 	}
-	return k
+	return dst, acc, bits
 }
 
 // Pack packs a n1-by-n2 matrix over Zq into a bit string {0,1}^(D*n1*n2)
 func (param *Parameters) Pack(C [][]uint16) []byte {
+	return param.PackTo(nil, C)
+}
 
-	n1, n2 := len(C), len(C[0])
-	b := make([]byte, param.D*n1*n2/8)
-	for i := 0; i < n1; i++ {
-		for j := 0; j < n2; j++ {
-			for l := 0; l < param.D; l++ {
-				if (uint16(1)<<uint(param.D-1-l))&C[i][j] != 0 {
-					index, shift := ((i*n2+j)*param.D+l)/8, uint(((i*n2+j)*param.D+l)&7)
-					b[index] |= byte(0x80) >> shift
-				}
-			}
+// PackTo appends the packing of the n1-by-n2 matrix C to dst and returns the extended slice, see Pack.
+// The rows are one bit string, a row may end inside a byte, the last byte is padded with zeros
+func (param *Parameters) PackTo(dst []byte, C [][]uint16) []byte {
+
+	var acc uint64
+	bits := 0
+	for i := range C {
+		if bits == 0 && param.d*len(C[i])%8 == 0 {
+			dst = param.packEntries(dst, C[i])
+			continue
 		}
+		dst, acc, bits = param.packBits(dst, C[i], acc, bits)
+	}
+	if bits > 0 {
+		dst = append(dst, byte(acc<<uint(8-bits)))
 	}
-	return b
+	return dst
+}
+
+// pack packs the low D bits of every entry of C
+func (param *Parameters) pack(C *matrix) []byte {
+	return param.packEntries(make([]byte, 0, param.d*len(C.data)/8), C.data)
+}
+
+// packEntries appends the low D bits of the entries c to dst, most significant bit first:
+// entries are big-endian words for D = 16, otherwise D bits at a time are shifted
+// into a 64-bit accumulator and taken out a byte at a time
+func (param *Parameters) packEntries(dst []byte, c []uint16) []byte {
+
+	b := grow(&dst, param.d*len(c)/8)
+	if param.kern != nil && len(c)%8 == 0 {
+		param.kern.pack(b, c)
+		return dst
+	}
+	if param.d == 16 {
+		for i, e := range c {
+			b[2*i], b[2*i+1] = byte(e>>8), byte(e)
+		}
+		return dst
+	}
+
+	var acc uint64
+	bits, j, mask := 0, 0, uint64(param.q)
+	for _, e := range c {
+		acc = acc<<uint(param.d) | uint64(e)&mask
+		for bits += param.d; bits >= 8; j++ {
+			bits -= 8
+			b[j] = byte(acc >> uint(bits))
+		}
+	}
+	return dst
+}
+
+// packBits appends the low D bits of the entries c to a bit string, most significant bit first,
+// the low bits of acc are not appended yet; it returns the bits left for the next entries
+func (param *Parameters) packBits(dst []byte, c []uint16, acc uint64, bits int) ([]byte, uint64, int) {
+
+	for _, e := range c {
+		acc = acc<<uint(param.d) | uint64(e&param.q)
+		for bits += param.d; bits >= 8; {
+			bits -= 8
+			dst = append(dst, byte(acc>>uint(bits)))
+		}
+	}
+	return dst, acc, bits
 }
 
 // Unpack unpacks a bit string {0,1}^(D*n1*n2) into a matrix (n1-by-n2) over Zq
 func (param *Parameters) Unpack(b []byte, n1, n2 int) [][]uint16 {
+	return param.unpack(b, n1, n2).slices()
+}
 
-	C := make([][]uint16, n1)
-	for i := range C {
-		C[i] = make([]uint16, n2)
-		for j := range C[i] {
-			for l := 0; l < param.D; l++ {
-				index, shift := ((i*n2+j)*param.D+l)/8, uint(((i*n2+j)*param.D+l)&7)
-				if b[index]&byte(0x80>>shift) != 0 {
-					C[i][j] |= uint16(1) << uint(param.D-1-l)
-				}
-			}
+// UnpackTo unpacks b into the matrix dst and returns dst, see Unpack.
+// The rows are one bit string, a row may start inside a byte of b
+func (param *Parameters) UnpackTo(dst [][]uint16, b []byte) [][]uint16 {
+
+	var acc uint64
+	bits := 0
+	for i := range dst {
+		if rowLen := param.d * len(dst[i]) / 8; bits == 0 && param.d*len(dst[i])%8 == 0 {
+			param.unpackEntries(dst[i], b[:rowLen])
+			b = b[rowLen:]
+			continue
 		}
+		b, acc, bits = param.unpackBits(dst[i], b, acc, bits)
 	}
-	return C
+	return dst
 }
 
-// Gen returns a pseudorandom matrix using SHAKE128
-func (param *Parameters) Gen(seed []byte) [][]uint16 {
+func (param *Parameters) unpack(b []byte, n1, n2 int) *matrix {
 
-	A := make([][]uint16, param.no)
-	for i := uint16(0); i < uint16(param.no); i++ {
+	C := newMatrix(n1, n2)
+	param.unpackEntries(C.data, b)
+	return C
+}
 
-		b := []byte{byte(i >> 8), byte(i)}
-		b = append(b, seed...)
-		shakeStr := param.shake(b, param.no*2)
+// unpackEntries unpacks the D·len(c) bits of b into the entries c:
+// entries are big-endian words for D = 16, otherwise bytes are shifted
+// into a 64-bit accumulator and D bits are taken at a time
+func (param *Parameters) unpackEntries(c []uint16, b []byte) {
 
-		A[i] = make([]uint16, param.no)
-		for j := 0; j < param.no; j++ {
-			A[i][j] = ((uint16(shakeStr[j*2]) << 8) | uint16(shakeStr[i*2+1])) & param.q
+	if param.kern != nil && len(c)%8 == 0 {
+		param.kern.unpack(c, b)
+		return
+	}
+	if param.d == 16 {
+		for i := range c {
+			c[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
 		}
+		return
 	}
 
-	return A
+	param.unpackBits(c, b, 0, 0)
 }
 
-// Sample returns a sample e from the distribution χ
-func (param *Parameters) Sample(r uint16) uint16 {
+// unpackBits is unpackEntries continuing a bit string, the low bits of acc are not taken yet;
+// it returns the rest of b and the bits left for the next entries
+func (param *Parameters) unpackBits(c []uint16, b []byte, acc uint64, bits int) ([]byte, uint64, int) {
 
-	e, t := uint16(0), r>>1
-	for z := 0; z < len(param.X)-1; z++ {
-		if t > param.X[z] {
-			e++
+	j := 0
+	for i := range c {
+		for ; bits < param.d; j, bits = j+1, bits+8 {
+			acc = acc<<8 | uint64(b[j])
 		}
+		bits -= param.d
+		c[i] = uint16(acc>>uint(bits)) & param.q
 	}
-	if r&1 != 0 && e != 0 {
-		e = (param.q - e + 1)
+	return b[j:], acc, bits
+}
+
+// Gen returns a pseudorandom matrix using SHAKE128 or AES128:
+// row i is SHAKE128(<i> || seed, 16·no) read as 16-bit little-endian entries,
+// or the encryptions AES128(seed, <i> || <j> || 0^96) of every eighth column j
+func (param *Parameters) Gen(seed []byte) [][]uint16 {
+	return param.genMatrix(seed).slices()
+}
+
+// Sample returns a sample e from the distribution χ,
+// it runs in constant time: the whole table is scanned without branches on r
+func (param *Parameters) Sample(r uint16) uint16 {
+
+	e, t, sign := uint16(0), r>>1, r&1
+	for z := 0; z < len(param.x)-1; z++ {
+		e += (param.x[z] - t) >> 15 // 1 if X[z] < t, both fit in 15 bits
 	}
-	return e
+	return ((-sign ^ e) + sign) & param.q // e or -e (mod q)
 }
 
 // SampleMatrix sample the n1-by-n2 matrix entry
 func (param *Parameters) SampleMatrix(r []byte, n1, n2 int) [][]uint16 {
+	return param.sampleMatrix(r, n1, n2).slices()
+}
 
-	E := make([][]uint16, n1)
-	for i := 0; i < n1; i++ {
-		E[i] = make([]uint16, n2)
-		for j := 0; j < n2; j++ {
-			index := (i*n2 + j) * 2
-			E[i][j] = param.Sample((uint16(r[index]) << 8) | uint16(r[index+1]))
-		}
+// SampleMatrixTo samples the entries of the matrix dst from r and returns dst, see SampleMatrix
+func (param *Parameters) SampleMatrixTo(dst [][]uint16, r []byte) [][]uint16 {
+
+	for i := range dst {
+		param.sampleEntries(dst[i], r[:2*len(dst[i])])
+		r = r[2*len(dst[i]):]
 	}
+	return dst
+}
+
+func (param *Parameters) sampleMatrix(r []byte, n1, n2 int) *matrix {
+
+	E := newMatrix(n1, n2)
+	param.sampleEntries(E.data, r)
 	return E
 }
+
+// sampleSecret samples the no-by-n matrix S of a key pair from r and returns Sᵀ,
+// which is sampled row by row as in the specification; Legacy sets sample S row by row
+func (param *Parameters) sampleSecret(r []byte) *matrix {
+
+	if param.gen == genLegacy {
+		return param.sampleMatrix(r, param.no, param.n).transpose()
+	}
+	return param.sampleMatrix(r, param.n, param.no)
+}
+
+// sampleEntries samples the entries e from the 16-bit little-endian words of r, like Sample
+// (big-endian for Legacy sets):
+// words are converted sampleBlock at a time, every entry of the table is compared
+// with the whole block, so that the loops are branch-free and the table stays in registers
+func (param *Parameters) sampleEntries(e []uint16, r []byte) {
+
+	if param.gen == genLegacy { // big-endian words
+		for i := range e {
+			e[i] = param.Sample(uint16(r[2*i])<<8 | uint16(r[2*i+1]))
+		}
+		return
+	}
+	if param.kern != nil {
+		param.kern.sample(e, r)
+		return
+	}
+	var t, sum [sampleBlock]uint16
+	X := param.x[:len(param.x)-1]
+	for lo := 0; lo < len(e); lo += sampleBlock {
+		block := e[lo:]
+		if len(block) > sampleBlock {
+			block = block[:sampleBlock]
+		}
+		for i := range block {
+			t[i], sum[i] = (uint16(r[2*(lo+i)])|uint16(r[2*(lo+i)+1])<<8)>>1, 0 // little-endian
+		}
+		for _, x := range X {
+			for i := range block {
+				sum[i] += (x - t[i]) >> 15 // 1 if x < t, both fit in 15 bits
+			}
+		}
+		for i := range block {
+			sign := uint16(r[2*(lo+i)]) & 1
+			block[i] = ((-sign ^ sum[i]) + sign) & param.q // e or -e (mod q)
+		}
+	}
+}
+
+// sampleBlock is the number of entries sampleEntries converts at once
+const sampleBlock = 64
@@ -1,11 +1,19 @@
 package frodo_test
 
 import (
+	"bytes"
+	"crypto/aes"
+	"encoding/asn1"
+	"errors"
 	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mariiatuzovska/frodo"
+	"golang.org/x/crypto/sha3"
 )
 
 // testing encryption & decryption
@@ -336,6 +344,140 @@ func TestGen1344(t *testing.T) {
 	}
 }
 
+// testing Gen against [FKEM] Algorithm 8
+// frodo pkg gen.go
+// A(i, j) = SHAKE128(<i> || seedA) entry j, both 16-bit little-endian,
+// every row is checked since the rows are expanded four at a time by the 4-way Keccak
+
+func testGenSpec(t *testing.T, param *frodo.Parameters, n int, q uint16) {
+
+	seed := make([]byte, 16)
+	rand.Seed(time.Now().UTC().UnixNano())
+	for i := range seed {
+		seed[i] = byte(rand.Int())
+	}
+
+	A := param.Gen(seed)
+	out := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		sha3.ShakeSum128(out, append([]byte{byte(i), byte(i >> 8)}, seed...))
+		for j := 0; j < n; j++ {
+			if e := (uint16(out[2*j]) | uint16(out[2*j+1])<<8) & q; A[i][j] != e {
+				t.Fatal("frodo_test.go/TestGenSpec: expected", e, "but has got", A[i][j], "at index", i, j)
+			}
+		}
+	}
+}
+
+func TestGenSpec640(t *testing.T) {
+	testGenSpec(t, frodo.Frodo640(), 640, 0x7fff)
+}
+
+func TestGenSpec976(t *testing.T) {
+	testGenSpec(t, frodo.Frodo976(), 976, 0xffff)
+}
+
+func TestGenSpec1344(t *testing.T) {
+	testGenSpec(t, frodo.Frodo1344(), 1344, 0xffff)
+}
+
+// testing AES Gen against [FKEM] Algorithm 7
+// frodo pkg gen.go
+// A(i, j..j+7) = AES128(seedA, <i> || <j> || 0^96), all 16-bit little-endian
+
+func testGenAESSpec(t *testing.T, param *frodo.Parameters, n int, q uint16) {
+
+	seed := make([]byte, 16)
+	rand.Seed(time.Now().UTC().UnixNano())
+	for i := range seed {
+		seed[i] = byte(rand.Int())
+	}
+
+	A := param.Gen(seed)
+	block, _ := aes.NewCipher(seed)
+	in, out := make([]byte, 16), make([]byte, 16)
+	for _, i := range []int{0, 1, 255, 256, n - 1} {
+		for j := 0; j < n; j += 8 {
+			in[0], in[1], in[2], in[3] = byte(i), byte(i>>8), byte(j), byte(j>>8)
+			block.Encrypt(out, in)
+			for k := 0; k < 8; k++ {
+				if e := (uint16(out[2*k]) | uint16(out[2*k+1])<<8) & q; A[i][j+k] != e {
+					t.Fatal("frodo_test.go/TestGenAESSpec: expected", e, "but has got", A[i][j+k], "at index", i, j+k)
+				}
+			}
+		}
+	}
+}
+
+func TestGenAESSpec640(t *testing.T) {
+	testGenAESSpec(t, frodo.Frodo640AES(), 640, 0x7fff)
+}
+
+func TestGenAESSpec976(t *testing.T) {
+	testGenAESSpec(t, frodo.Frodo976AES(), 976, 0xffff)
+}
+
+func TestGenAESSpec1344(t *testing.T) {
+	testGenAESSpec(t, frodo.Frodo1344AES(), 1344, 0xffff)
+}
+
+// testing frodo KEM & PKE with AES parameter sets
+// frodo pkg kem.go, pke.go
+
+func TestFrodoAES(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640AES(), frodo.Frodo976AES(), frodo.Frodo1344AES()} {
+
+		pk, sk := param.EncapsKeyGen()
+		ct, ss := param.Encaps(pk)
+		if !bytes.Equal(ss, param.Decaps(ct, sk)) {
+			t.Error("frodo_test.go/TestFrodoAES: expected eq secrets for", param.Name())
+		}
+
+		m := make([]byte, len(ss))
+		rand.Read(m)
+		ppk, psk := param.KeyGen()
+		if !bytes.Equal(m, param.Dec(param.Enc(m, ppk), psk)) {
+			t.Error("frodo_test.go/TestFrodoAES: expected eq messages for", param.Name())
+		}
+	}
+}
+
+// testing keys created with the legacy derivation of A
+// frodo pkg gen.go
+
+func TestLegacyGen(t *testing.T) {
+
+	param := frodo.Frodo976()
+	legacy := param.Legacy()
+
+	seed := make([]byte, 16)
+	rand.Seed(time.Now().UTC().UnixNano())
+	for i := range seed {
+		seed[i] = byte(rand.Int())
+	}
+
+	A := legacy.Gen(seed)
+	for _, i := range []int{0, 7, 975} {
+		out := make([]byte, 2*976)
+		sha3.ShakeSum256(out, append([]byte{byte(i >> 8), byte(i)}, seed...))
+		for j := 0; j < 976; j++ {
+			if e := uint16(out[2*j])<<8 | uint16(out[2*i+1]); A[i][j] != e {
+				t.Fatal("frodo_test.go/TestLegacyGen: expected", e, "but has got", A[i][j], "at index", i, j)
+			}
+		}
+	}
+
+	pk, sk := legacy.EncapsKeyGen()
+	ct, ss := legacy.Encaps(pk)
+	if !bytes.Equal(ss, legacy.Decaps(ct, sk)) {
+		t.Error("frodo_test.go/TestLegacyGen: expected eq secrets with the legacy derivation")
+	}
+	if _, err := param.DecapsChecked(ct, sk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Error("frodo_test.go/TestLegacyGen: expected a legacy key to be rejected by the spec derivation, got", err)
+	}
+}
+
 // testing Error matrices
 // frodo pkg frodo.go
 // from eq seeds it should be eq matrices
@@ -405,3 +547,1019 @@ func TestSample1344(t *testing.T) {
 		}
 	}
 }
+
+// testing randomness sources
+// frodo pkg pke.go, kem.go
+// from eq sources it should be eq keys, source failures should be returned
+
+type failingReader struct{}
+
+func (failingReader) Read(b []byte) (int, error) {
+	return 0, errors.New("source is broken")
+}
+
+func TestEncapsKeyGenFrom(t *testing.T) {
+
+	frodo := frodo.Frodo640()
+
+	pk1, sk1, err := frodo.EncapsKeyGenFrom(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncapsKeyGenFrom:", err)
+	}
+	pk2, sk2, err := frodo.EncapsKeyGenFrom(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncapsKeyGenFrom:", err)
+	}
+
+	if !bytes.Equal(pk1.SeedA, pk2.SeedA) || !bytes.Equal(pk1.B, pk2.B) || !bytes.Equal(sk1.SeedS, sk2.SeedS) {
+		t.Error("frodo_test.go/TestEncapsKeyGenFrom: expected eq key pairs from eq sources")
+	}
+
+	ct1, ss1, err := frodo.EncapsFrom(rand.New(rand.NewSource(2)), pk1)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncapsKeyGenFrom:", err)
+	}
+	ct2, ss2, err := frodo.EncapsFrom(rand.New(rand.NewSource(2)), pk1)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncapsKeyGenFrom:", err)
+	}
+
+	if !bytes.Equal(ct1.C1, ct2.C1) || !bytes.Equal(ct1.C2, ct2.C2) || !bytes.Equal(ss1, ss2) {
+		t.Error("frodo_test.go/TestEncapsKeyGenFrom: expected eq encapsulations from eq sources")
+	}
+	if !bytes.Equal(ss1, frodo.Decaps(ct1, sk1)) {
+		t.Error("frodo_test.go/TestEncapsKeyGenFrom: expected eq secrets")
+	}
+}
+
+func TestRandomnessFailure(t *testing.T) {
+
+	frodo := frodo.Frodo640()
+
+	if _, _, err := frodo.KeyGenFrom(failingReader{}); err == nil {
+		t.Error("frodo_test.go/TestRandomnessFailure: KeyGenFrom expected error")
+	}
+	if _, _, err := frodo.EncapsKeyGenFrom(failingReader{}); err == nil {
+		t.Error("frodo_test.go/TestRandomnessFailure: EncapsKeyGenFrom expected error")
+	}
+
+	pk, _ := frodo.KeyGen()
+	if _, err := frodo.EncFrom(failingReader{}, make([]byte, 16), pk); err == nil {
+		t.Error("frodo_test.go/TestRandomnessFailure: EncFrom expected error")
+	}
+
+	epk, _ := frodo.EncapsKeyGen()
+	if _, _, err := frodo.EncapsFrom(failingReader{}, epk); err == nil {
+		t.Error("frodo_test.go/TestRandomnessFailure: EncapsFrom expected error")
+	}
+}
+
+// testing serialization of keys & ciphertexts
+// frodo pkg encoding.go
+// sizes are given in [FKEM] Table 5
+
+func testMarshal(t *testing.T, param *frodo.Parameters, pkLen, skLen, ctLen int) {
+
+	pk, sk := param.EncapsKeyGen()
+	ct, ss := param.Encaps(pk)
+
+	pkb, _ := pk.MarshalBinary()
+	skb, err := sk.MarshalBinary()
+	if err != nil {
+		t.Fatal("frodo_test.go/TestMarshal:", err)
+	}
+	ctb, _ := ct.MarshalBinary()
+	if len(pkb) != pkLen || len(skb) != skLen || len(ctb) != ctLen {
+		t.Fatal("frodo_test.go/TestMarshal: expected sizes", pkLen, skLen, ctLen, "but has got", len(pkb), len(skb), len(ctb))
+	}
+
+	pk2, err := param.UnmarshalEncapsPublicKey(pkb)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestMarshal:", err)
+	}
+	sk2, err := param.UnmarshalEncapsSecretKey(skb)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestMarshal:", err)
+	}
+	ct2, err := param.UnmarshalEncapsCipherText(ctb)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestMarshal:", err)
+	}
+
+	if !bytes.Equal(ss, param.Decaps(ct2, sk2)) {
+		t.Error("frodo_test.go/TestMarshal: expected eq secrets after unmarshalling")
+	}
+	if skb2, _ := sk2.MarshalBinary(); !bytes.Equal(skb, skb2) {
+		t.Error("frodo_test.go/TestMarshal: expected eq encodings of the secret key")
+	}
+	ct3, ss3 := param.Encaps(pk2)
+	if !bytes.Equal(ss3, param.Decaps(ct3, sk)) {
+		t.Error("frodo_test.go/TestMarshal: expected eq secrets with the unmarshalled public key")
+	}
+
+	if _, err := param.UnmarshalEncapsPublicKey(pkb[1:]); err == nil {
+		t.Error("frodo_test.go/TestMarshal: expected error for a short public key")
+	}
+	if _, err := param.UnmarshalEncapsSecretKey(append(skb, 0)); err == nil {
+		t.Error("frodo_test.go/TestMarshal: expected error for a long secret key")
+	}
+	if _, err := param.UnmarshalEncapsCipherText(ctb[:ctLen-1]); err == nil {
+		t.Error("frodo_test.go/TestMarshal: expected error for a short ciphertext")
+	}
+	if err := new(frodo.EncapsPublicKey).UnmarshalBinary(pkb); err == nil {
+		t.Error("frodo_test.go/TestMarshal: expected error for a key without parameter set")
+	}
+	if err := pk.UnmarshalBinary(pkb); err != nil {
+		t.Error("frodo_test.go/TestMarshal:", err)
+	}
+}
+
+func TestMarshal640(t *testing.T) {
+	testMarshal(t, frodo.Frodo640(), 9616, 19888, 9720)
+}
+
+func TestMarshal976(t *testing.T) {
+	testMarshal(t, frodo.Frodo976(), 15632, 31296, 15744)
+}
+
+func TestMarshal1344(t *testing.T) {
+	testMarshal(t, frodo.Frodo1344(), 21520, 43088, 21632)
+}
+
+// testing ISO parameter sets, salted ciphertexts & ephemeral sets
+// frodo pkg frodo.go, kem.go, encoding.go
+
+func TestMarshalISO(t *testing.T) {
+	testMarshal(t, frodo.FrodoKEM640SHAKE(), 9616, 19888, 9752)
+	testMarshal(t, frodo.FrodoKEM976AES(), 15632, 31296, 15792)
+	testMarshal(t, frodo.FrodoKEM1344SHAKE(), 21520, 43088, 21696)
+	testMarshal(t, frodo.EFrodoKEM640AES(), 9616, 19888, 9720)
+	testMarshal(t, frodo.EFrodoKEM976SHAKE(), 15632, 31296, 15744)
+	testMarshal(t, frodo.EFrodoKEM1344AES(), 21520, 43088, 21632)
+}
+
+func TestSaltISO(t *testing.T) {
+
+	param := frodo.FrodoKEM640SHAKE()
+
+	pk, sk := param.EncapsKeyGen()
+	ct, ss := param.Encaps(pk)
+	if len(ct.Salt) != 32 {
+		t.Fatal("frodo_test.go/TestSaltISO: expected 32-byte salt, but has got", len(ct.Salt))
+	}
+
+	ct.Salt[0] ^= 1
+	if bytes.Equal(ss, param.Decaps(ct, sk)) {
+		t.Error("frodo_test.go/TestSaltISO: expected a different secret for a modified salt")
+	}
+
+	seed := rand.New(rand.NewSource(3))
+	pk2019, _, _ := frodo.Frodo640().EncapsKeyGenFrom(seed)
+	seed = rand.New(rand.NewSource(3))
+	pkISO, _, _ := param.EncapsKeyGenFrom(seed)
+	if bytes.Equal(pk2019.B, pkISO.B) {
+		t.Error("frodo_test.go/TestSaltISO: expected different keys for the 2019 and ISO seedSE")
+	}
+}
+
+// testing validation of untrusted input
+// frodo pkg errors.go
+// malformed keys & ciphertexts should be errors, not panics
+
+func TestValidateKEM(t *testing.T) {
+
+	param := frodo.Frodo640()
+	pk, sk := param.EncapsKeyGen()
+	ct, _ := param.Encaps(pk)
+
+	short := *ct
+	short.C1 = short.C1[:10]
+	if _, err := param.DecapsChecked(&short, sk); !errors.Is(err, frodo.ErrInvalidCiphertext) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrInvalidCiphertext for short c1, got", err)
+	}
+	if _, err := param.DecapsChecked(nil, sk); !errors.Is(err, frodo.ErrInvalidCiphertext) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrInvalidCiphertext for nil, got", err)
+	}
+
+	wrong := *pk
+	wrong.B = append(wrong.B, 0)
+	if _, _, err := param.EncapsFrom(rand.New(rand.NewSource(1)), &wrong); !errors.Is(err, frodo.ErrInvalidPublicKey) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrInvalidPublicKey for long b, got", err)
+	}
+
+	other := frodo.Frodo976()
+	if _, _, err := other.EncapsFrom(rand.New(rand.NewSource(1)), pk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrParameterMismatch, got", err)
+	}
+	if _, err := frodo.Frodo640AES().DecapsChecked(ct, sk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrParameterMismatch for AES, got", err)
+	}
+
+	skb, _ := sk.MarshalBinary()
+	skb[16+9616] = 0x40 // first entry of Sᵀ is out of χ
+	if _, err := param.UnmarshalEncapsSecretKey(skb); !errors.Is(err, frodo.ErrInvalidSecretKey) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrInvalidSecretKey for large S, got", err)
+	}
+	if _, err := param.UnmarshalEncapsCipherText(nil); !errors.Is(err, frodo.ErrInvalidCiphertext) {
+		t.Error("frodo_test.go/TestValidateKEM: expected ErrInvalidCiphertext for empty ciphertext, got", err)
+	}
+}
+
+func TestValidatePKE(t *testing.T) {
+
+	param := frodo.Frodo976()
+	pk, sk := param.KeyGen()
+
+	if _, err := param.EncFrom(rand.New(rand.NewSource(1)), make([]byte, 23), pk); !errors.Is(err, frodo.ErrInvalidMessage) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrInvalidMessage for short message, got", err)
+	}
+
+	wrong := *pk
+	wrong.B = wrong.B[:100]
+	if _, err := param.EncFrom(rand.New(rand.NewSource(1)), make([]byte, 24), &wrong); !errors.Is(err, frodo.ErrInvalidPublicKey) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrInvalidPublicKey for short B, got", err)
+	}
+
+	cipher := param.Enc(make([]byte, 24), pk)
+	cipher.C2 = cipher.C2[:7]
+	if _, err := param.DecChecked(cipher, sk); !errors.Is(err, frodo.ErrInvalidCiphertext) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrInvalidCiphertext for short C2, got", err)
+	}
+
+	if _, err := frodo.Frodo640().DecChecked(param.Enc(make([]byte, 24), pk), sk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrParameterMismatch for 976 key, got", err)
+	}
+	if _, err := frodo.Frodo640().DecChecked(param.Enc(make([]byte, 24), pk), &frodo.SecretKey{S: sk.S}); !errors.Is(err, frodo.ErrInvalidSecretKey) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrInvalidSecretKey for unbound 976 key, got", err)
+	}
+	if _, err := frodo.Frodo640().EncFrom(rand.New(rand.NewSource(1)), make([]byte, 16), pk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Error("frodo_test.go/TestValidatePKE: expected ErrParameterMismatch for 976 public key, got", err)
+	}
+	if pk.Scheme() != frodo.Scheme(param) || sk.Scheme() != frodo.Scheme(param) || cipher.Scheme() != frodo.Scheme(param) {
+		t.Error("frodo_test.go/TestValidatePKE: keys and ciphertexts must record their parameter set")
+	}
+}
+
+// testing the registry of parameter sets
+// frodo pkg registry.go
+
+func TestRegistry(t *testing.T) {
+
+	names := frodo.Names()
+	if len(names) != 18 {
+		t.Fatalf("frodo_test.go/TestRegistry: expected 18 parameter sets, got %d", len(names))
+	}
+	for _, name := range names {
+		param, err := frodo.Lookup(name)
+		if err != nil || param.Name() != name {
+			t.Errorf("frodo_test.go/TestRegistry: Lookup(%q) returned %v, %v", name, param, err)
+		}
+		if oid, ok := param.OID(); ok { // no OID is built in, see registry.go
+			t.Errorf("frodo_test.go/TestRegistry: %s has the OID %s before RegisterOID", name, oid)
+		}
+	}
+	if param, err := frodo.Lookup("frodokem-976-aes"); err != nil || param != frodo.FrodoKEM976AES() {
+		t.Error("frodo_test.go/TestRegistry: Lookup must ignore the case, got", err)
+	}
+	if _, err := frodo.Lookup("FrodoKEM-512-SHAKE"); !errors.Is(err, frodo.ErrUnknownParameters) {
+		t.Error("frodo_test.go/TestRegistry: expected ErrUnknownParameters, got", err)
+	}
+
+	oid := asn1.ObjectIdentifier{1, 3, 9999, 99, 1} // private test arc
+	param := frodo.FrodoKEM640SHAKE()
+	if _, err := frodo.LookupOID(oid); !errors.Is(err, frodo.ErrUnknownParameters) {
+		t.Error("frodo_test.go/TestRegistry: expected ErrUnknownParameters for an unregistered OID, got", err)
+	}
+	if err := frodo.RegisterOID(oid, param.WithConcurrency(2)); err != nil {
+		t.Fatal("frodo_test.go/TestRegistry:", err)
+	}
+	if got, err := frodo.LookupOID(oid); err != nil || got != param {
+		t.Error("frodo_test.go/TestRegistry: LookupOID must return the registered set, got", err)
+	}
+	if got, ok := param.OID(); !ok || !got.Equal(oid) {
+		t.Error("frodo_test.go/TestRegistry: OID returned", got, ok)
+	}
+	if err := frodo.RegisterOID(oid, param); err != nil {
+		t.Error("frodo_test.go/TestRegistry: registering the same OID twice failed:", err)
+	}
+	if frodo.RegisterOID(oid, frodo.FrodoKEM640AES()) == nil || frodo.RegisterOID(asn1.ObjectIdentifier{1, 3, 9999, 99, 2}, param) == nil {
+		t.Error("frodo_test.go/TestRegistry: an OID and a set must be bound only once")
+	}
+	if frodo.RegisterOID(asn1.ObjectIdentifier{1, 3, 9999, 99, 3}, param.Legacy()) == nil {
+		t.Error("frodo_test.go/TestRegistry: unregistered sets must not get an OID")
+	}
+}
+
+// testing custom parameter sets
+// frodo pkg config.go
+
+func TestNewParameters(t *testing.T) {
+
+	for _, name := range frodo.Names() {
+		param, _ := frodo.Lookup(name)
+		if same, err := frodo.NewParameters(param.Config()); err != nil || same != param {
+			t.Fatalf("frodo_test.go/TestNewParameters: the configuration of %s returned %v", name, err)
+		}
+
+		c := param.Config()
+		c.Name += "-custom"
+		custom, err := frodo.NewParameters(c)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestNewParameters:", err)
+		}
+		seed, mu := make([]byte, param.SeedSize()), make([]byte, param.EncapsulationSeedSize())
+		rand.Read(seed)
+		rand.Read(mu)
+		pk, sk, _ := param.DeriveKeyPair(seed)
+		ct, ss, _ := param.EncapsulateDeterministic(pk, mu)
+		pk1, sk1, _ := custom.DeriveKeyPair(seed)
+		ct1, ss1, _ := custom.EncapsulateDeterministic(pk1, mu)
+		if !bytes.Equal(pk1.Bytes(), pk.Bytes()) || !bytes.Equal(sk1.Bytes(), sk.Bytes()) || !bytes.Equal(ct1, ct) || !bytes.Equal(ss1, ss) {
+			t.Errorf("frodo_test.go/TestNewParameters: %s differs from %s", custom.Name(), name)
+		}
+		if _, err := custom.Decapsulate(sk, ct); !errors.Is(err, frodo.ErrParameterMismatch) {
+			t.Errorf("frodo_test.go/TestNewParameters: expected ErrParameterMismatch for a key of %s, got %v", name, err)
+		}
+	}
+
+	c := frodo.FrodoKEM640AES().Config()
+	for _, invalid := range []func(c *frodo.Config){
+		func(c *frodo.Config) { c.Name = "" },
+		func(c *frodo.Config) { c.N = 644 },
+		func(c *frodo.Config) { c.NBar = 0 },
+		func(c *frodo.Config) { c.D = 17 },
+		func(c *frodo.Config) { c.B = 16 },
+		func(c *frodo.Config) { c.B, c.MBar, c.NBar = 3, 3, 1 },
+		func(c *frodo.Config) { c.ErrorTable = nil },
+		func(c *frodo.Config) { c.ErrorTable = []uint16{20000, 10000, 32767} },
+		func(c *frodo.Config) { c.ErrorTable = []uint16{10000, 32766} },
+		func(c *frodo.Config) { c.ErrorTable = []uint16{10000, 32768} },
+		func(c *frodo.Config) { c.Generator = "SHA3" },
+		func(c *frodo.Config) { c.SHAKE = "SHAKE512" },
+		func(c *frodo.Config) { c.SeedABytes = 32 },
+		func(c *frodo.Config) { c.SecretBytes = 0 },
+		func(c *frodo.Config) { c.SaltBytes = -1 },
+		func(c *frodo.Config) { c.B = 3 }, // a registered name with another set
+	} {
+		d := c
+		d.ErrorTable = append([]uint16(nil), c.ErrorTable...)
+		invalid(&d)
+		if _, err := frodo.NewParameters(d); !errors.Is(err, frodo.ErrInvalidParameters) {
+			t.Errorf("frodo_test.go/TestNewParameters: expected ErrInvalidParameters for %+v, got %v", d, err)
+		}
+	}
+}
+
+func TestUnalignedParameters(t *testing.T) {
+
+	// rows of n̄ = 4 entries are B·n̄ = 12 and D·n̄ = 60 bits, they end inside a byte
+	param, err := frodo.NewParameters(frodo.Config{Name: "FrodoKEM-128-unaligned", N: 128, MBar: 8, NBar: 4, D: 15, B: 3,
+		ErrorTable: frodo.Frodo1344().ErrorTable(), Generator: "SHAKE128", SHAKE: "SHAKE128",
+		SeedABytes: 16, SecretBytes: 16, SeedSEBytes: 32, SaltBytes: 32})
+	if err != nil {
+		t.Fatal("frodo_test.go/TestUnalignedParameters:", err)
+	}
+
+	pk, sk := param.EncapsKeyGen()
+	ct, ss := param.Encaps(pk)
+	if ss1, err := param.DecapsChecked(ct, sk); err != nil || !bytes.Equal(ss1, ss) {
+		t.Errorf("frodo_test.go/TestUnalignedParameters: %s decapsulates a different secret: %v", param.Name(), err)
+	}
+
+	k := make([]byte, 3*8*4/8)
+	rand.Read(k)
+	K := matrixOf(make([]uint16, 8*4), 8, 4)
+	param.EncodeTo(K, k)
+	if !reflect.DeepEqual(K, param.Encode(k)) || !bytes.Equal(param.Decode(K), k) || !bytes.Equal(param.DecodeTo(nil, K), k) {
+		t.Error("frodo_test.go/TestUnalignedParameters: encoding of rows of 12 bits does not round-trip")
+	}
+	packed := param.Pack(K)
+	if len(packed) != 15*8*4/8 || !reflect.DeepEqual(param.Unpack(packed, 8, 4), K) ||
+		!reflect.DeepEqual(param.UnpackTo(matrixOf(make([]uint16, 8*4), 8, 4), packed), K) {
+		t.Error("frodo_test.go/TestUnalignedParameters: packing of rows of 60 bits does not round-trip")
+	}
+}
+
+func TestLoadParameters(t *testing.T) {
+
+	sets, err := frodo.LoadParametersFile("testdata/params_experimental.json")
+	if err != nil || len(sets) != 2 {
+		t.Fatal("frodo_test.go/TestLoadParameters:", err)
+	}
+	for _, param := range append(sets, sets[0].WithConcurrency(3)) {
+		pk, sk := param.EncapsKeyGen()
+		ct, ss := param.Encaps(pk)
+		if ss1, err := param.DecapsChecked(ct, sk); err != nil || !bytes.Equal(ss1, ss) {
+			t.Errorf("frodo_test.go/TestLoadParameters: %s decapsulates a different secret: %v", param.Name(), err)
+		}
+		ppk, psk := param.KeyGen()
+		message := make([]byte, param.ExtractedBits()*param.MBar()*param.NBar()/8)
+		rand.Read(message)
+		if m, err := param.DecChecked(param.Enc(message, ppk), psk); err != nil || !bytes.Equal(m, message) {
+			t.Errorf("frodo_test.go/TestLoadParameters: %s decrypts a different message: %v", param.Name(), err)
+		}
+	}
+
+	one, err := frodo.LoadParameters(strings.NewReader(`{"name": "FrodoKEM-640-SHAKE", "n": 640, "mbar": 8, "nbar": 8, "d": 15, "b": 2,
+		"errorTable": [4643, 13363, 20579, 25843, 29227, 31145, 32103, 32525, 32689, 32745, 32762, 32766, 32767],
+		"generator": "shake128", "shake": "shake128", "seedABytes": 16, "secretBytes": 16, "seedSEBytes": 32, "saltBytes": 32}`))
+	if err != nil || len(one) != 1 || one[0] != frodo.FrodoKEM640SHAKE() {
+		t.Error("frodo_test.go/TestLoadParameters: a single object must load FrodoKEM-640-SHAKE, got", err)
+	}
+	if _, err := frodo.LoadParameters(strings.NewReader(`{"name": "x", "q": 4096}`)); !errors.Is(err, frodo.ErrInvalidParameters) {
+		t.Error("frodo_test.go/TestLoadParameters: expected ErrInvalidParameters for an unknown key, got", err)
+	}
+}
+
+// testing the generic KEM scheme
+// frodo pkg scheme.go
+
+func TestScheme(t *testing.T) {
+
+	schemes := []frodo.Scheme{frodo.Frodo640(), frodo.Frodo976AES(), frodo.FrodoKEM1344SHAKE(), frodo.EFrodoKEM640AES()}
+	for _, scheme := range schemes {
+
+		pk, sk, err := scheme.GenerateKeyPair()
+		if err != nil {
+			t.Fatal("frodo_test.go/TestScheme:", err)
+		}
+		if len(pk.Bytes()) != scheme.PublicKeySize() || len(sk.Bytes()) != scheme.PrivateKeySize() {
+			t.Error("frodo_test.go/TestScheme: unexpected key sizes for", scheme.Name())
+		}
+
+		ct, ss, err := scheme.Encapsulate(pk)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestScheme:", err)
+		}
+		if len(ct) != scheme.CiphertextSize() || len(ss) != scheme.SharedKeySize() {
+			t.Error("frodo_test.go/TestScheme: unexpected ciphertext sizes for", scheme.Name())
+		}
+		if s2, err := scheme.Decapsulate(sk, ct); err != nil || !bytes.Equal(ss, s2) {
+			t.Error("frodo_test.go/TestScheme: expected eq secrets for", scheme.Name(), err)
+		}
+
+		pk2, err := scheme.UnmarshalBinaryPublicKey(pk.Bytes())
+		if err != nil {
+			t.Fatal("frodo_test.go/TestScheme:", err)
+		}
+		sk2, err := scheme.UnmarshalBinaryPrivateKey(sk.Bytes())
+		if err != nil {
+			t.Fatal("frodo_test.go/TestScheme:", err)
+		}
+		ss3, ct3 := pk2.Encapsulate()
+		if s3, err := sk2.Decapsulate(ct3); err != nil || !bytes.Equal(ss3, s3) {
+			t.Error("frodo_test.go/TestScheme: expected eq secrets with unmarshalled keys for", scheme.Name(), err)
+		}
+		if !bytes.Equal(sk.EncapsulationKey().Bytes(), pk.Bytes()) || pk2.Scheme().Name() != scheme.Name() {
+			t.Error("frodo_test.go/TestScheme: expected the public key of the secret key for", scheme.Name())
+		}
+
+		seed := make([]byte, scheme.SeedSize())
+		rand.Read(seed)
+		dpk1, _, err := scheme.DeriveKeyPair(seed)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestScheme:", err)
+		}
+		dpk2, _, _ := scheme.DeriveKeyPair(seed)
+		if !bytes.Equal(dpk1.Bytes(), dpk2.Bytes()) {
+			t.Error("frodo_test.go/TestScheme: expected eq derived keys for", scheme.Name())
+		}
+		if _, _, err := scheme.DeriveKeyPair(seed[1:]); err == nil {
+			t.Error("frodo_test.go/TestScheme: expected error for a short seed")
+		}
+	}
+}
+
+// testing seed-only secret keys
+// frodo pkg scheme.go, encoding.go
+
+func TestSeedOnlyKey(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.FrodoKEM976AES(), frodo.EFrodoKEM1344SHAKE()} {
+
+		seed := make([]byte, param.SeedSize())
+		rand.Read(seed)
+		pk, sk, err := param.DeriveKeyPair(seed)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestSeedOnlyKey:", err)
+		}
+
+		stored, err := sk.MarshalSeed()
+		if err != nil || !bytes.Equal(stored, seed) {
+			t.Fatal("frodo_test.go/TestSeedOnlyKey: expected the master seed, got", stored, err)
+		}
+		sk2, err := param.UnmarshalEncapsSecretKeySeed(stored)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestSeedOnlyKey:", err)
+		}
+		if !bytes.Equal(sk.Bytes(), sk2.Bytes()) {
+			t.Error("frodo_test.go/TestSeedOnlyKey: expected eq secret keys from the seed for", param.Name())
+		}
+
+		ct, ss := param.Encaps(pk)
+		if !bytes.Equal(ss, param.Decaps(ct, sk2)) {
+			t.Error("frodo_test.go/TestSeedOnlyKey: expected eq secrets for", param.Name())
+		}
+	}
+
+	_, sk := frodo.Frodo640().EncapsKeyGen()
+	if _, err := sk.MarshalSeed(); !errors.Is(err, frodo.ErrInvalidSecretKey) {
+		t.Error("frodo_test.go/TestSeedOnlyKey: expected error for a key without seed, got", err)
+	}
+}
+
+// testing derandomized encapsulation & encryption
+// frodo pkg scheme.go, pke.go
+// explicit coins should replay the randomized operations
+
+func TestEncapsulateDeterministic(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.FrodoKEM640AES()} {
+
+		pk, sk := param.EncapsKeyGen()
+		mu := make([]byte, param.EncapsulationSeedSize())
+		rand.Read(mu)
+
+		ct1, ss1, err := param.EncapsulateDeterministic(pk, mu)
+		if err != nil {
+			t.Fatal("frodo_test.go/TestEncapsulateDeterministic:", err)
+		}
+		ct, ss, _ := param.EncapsFrom(bytes.NewReader(mu), pk)
+		ct2, _ := ct.MarshalBinary()
+		if !bytes.Equal(ct1, ct2) || !bytes.Equal(ss1, ss) {
+			t.Error("frodo_test.go/TestEncapsulateDeterministic: expected the randomized encapsulation for", param.Name())
+		}
+		if s, err := param.Decapsulate(sk, ct1); err != nil || !bytes.Equal(s, ss1) {
+			t.Error("frodo_test.go/TestEncapsulateDeterministic: expected eq secrets for", param.Name(), err)
+		}
+		if _, _, err := param.EncapsulateDeterministic(pk, mu[1:]); err == nil {
+			t.Error("frodo_test.go/TestEncapsulateDeterministic: expected error for short coins")
+		}
+	}
+}
+
+func TestEncryptWithSeed(t *testing.T) {
+
+	param := frodo.Frodo976()
+	pk, sk := param.KeyGen()
+
+	m, seedSE := make([]byte, 24), make([]byte, 24)
+	rand.Read(m)
+	rand.Read(seedSE)
+
+	c1, err := param.EncryptWithSeed(m, pk, seedSE)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncryptWithSeed:", err)
+	}
+	c2, _ := param.EncFrom(bytes.NewReader(seedSE), m, pk)
+	for i := range c1.C2 {
+		for j := range c1.C2[i] {
+			if c1.C2[i][j] != c2.C2[i][j] {
+				t.Fatal("frodo_test.go/TestEncryptWithSeed: expected the randomized ciphertext")
+			}
+		}
+	}
+	if !bytes.Equal(m, param.Dec(c1, sk)) {
+		t.Error("frodo_test.go/TestEncryptWithSeed: expected eq messages")
+	}
+	if _, err := param.EncryptWithSeed(m, pk, seedSE[:5]); err == nil {
+		t.Error("frodo_test.go/TestEncryptWithSeed: expected error for a short seed")
+	}
+}
+
+func benchmarkKEM(b *testing.B, param *frodo.Parameters) {
+
+	pk, sk := param.EncapsKeyGen()
+	ct, _ := param.Encaps(pk)
+	b.Run("KeyGen", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			param.EncapsKeyGen()
+		}
+	})
+	b.Run("Encaps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			param.Encaps(pk)
+		}
+	})
+	b.Run("Decaps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			param.Decaps(ct, sk)
+		}
+	})
+
+	s := param.NewScratch()
+	ctBuf, ssBuf := make([]byte, 0, param.CiphertextSize()), make([]byte, 0, param.SharedKeySize())
+	encapsulate := func() { ctBuf, ssBuf, _ = s.EncapsulateTo(ctBuf[:0], ssBuf[:0], pk) }
+	decapsulate := func() { ssBuf, _ = s.DecapsulateTo(ssBuf[:0], sk, ctBuf) }
+	encapsulate()
+	b.Run("EncapsulateTo", func(b *testing.B) {
+		if allocs := testing.AllocsPerRun(3, encapsulate); allocs != 0 {
+			b.Fatalf("EncapsulateTo allocates %v times", allocs)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encapsulate()
+		}
+	})
+	b.Run("DecapsulateTo", func(b *testing.B) {
+		if allocs := testing.AllocsPerRun(3, decapsulate); allocs != 0 {
+			b.Fatalf("DecapsulateTo allocates %v times", allocs)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			decapsulate()
+		}
+	})
+}
+
+func BenchmarkFrodo640(b *testing.B)  { benchmarkKEM(b, frodo.Frodo640()) }
+func BenchmarkFrodo976(b *testing.B)  { benchmarkKEM(b, frodo.Frodo976()) }
+func BenchmarkFrodo1344(b *testing.B) { benchmarkKEM(b, frodo.Frodo1344()) }
+
+func TestConcurrency(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.FrodoKEM976AES(), frodo.Frodo640().Legacy()} {
+		seed, mu := make([]byte, param.SeedSize()), make([]byte, param.EncapsulationSeedSize())
+		rand.Read(seed)
+		rand.Read(mu)
+		pk, sk, _ := param.DeriveKeyPair(seed)
+		ct, ss, _ := param.EncapsulateDeterministic(pk, mu)
+		A := param.Gen(seed[:16])
+
+		for _, n := range []int{2, 3, 7, 64, 1000} {
+			parallel := param.WithConcurrency(n)
+			if parallel.Concurrency() != n || param.Concurrency() != 1 {
+				t.Fatalf("frodo_test.go/TestConcurrency: %s got %d goroutines", param.Name(), parallel.Concurrency())
+			}
+			pk1, sk1, _ := parallel.DeriveKeyPair(seed)
+			ct1, ss1, _ := parallel.EncapsulateDeterministic(pk1, mu)
+			if !bytes.Equal(pk1.Bytes(), pk.Bytes()) || !bytes.Equal(sk1.Bytes(), sk.Bytes()) ||
+				!bytes.Equal(ct1, ct) || !bytes.Equal(ss1, ss) {
+				t.Errorf("frodo_test.go/TestConcurrency: %s with %d goroutines differs from the sequential path", param.Name(), n)
+			}
+			if ss2, err := parallel.Decapsulate(sk, ct); err != nil || !bytes.Equal(ss2, ss) {
+				t.Errorf("frodo_test.go/TestConcurrency: %s with %d goroutines decapsulates a different secret", param.Name(), n)
+			}
+			A1 := parallel.Gen(seed[:16])
+			for i := range A {
+				if !bytes.Equal(parallel.Pack([][]uint16{A1[i]}), param.Pack([][]uint16{A[i]})) {
+					t.Fatalf("frodo_test.go/TestConcurrency: %s with %d goroutines generates a different row %d of A", param.Name(), n, i)
+				}
+			}
+		}
+	}
+}
+
+func TestSharedParameters(t *testing.T) {
+
+	param := frodo.FrodoKEM640SHAKE()
+	if frodo.FrodoKEM640SHAKE() != param || frodo.Frodo640() == param {
+		t.Fatal("frodo_test.go/TestSharedParameters: constructors must return one value per set")
+	}
+	if param.Dimension() != 640 || param.MBar() != 8 || param.NBar() != 8 || param.ModulusBits() != 15 || param.ExtractedBits() != 2 {
+		t.Error("frodo_test.go/TestSharedParameters: unexpected dimensions of", param.Name())
+	}
+	X := param.ErrorTable()
+	X[0]++
+	if param.ErrorTable()[0] == X[0] || param.Sample(2*4643) != 0 {
+		t.Error("frodo_test.go/TestSharedParameters: the error table of", param.Name(), "can be modified")
+	}
+	if param.Legacy() == param || param.WithConcurrency(2) == param || param.Concurrency() != 1 {
+		t.Error("frodo_test.go/TestSharedParameters: Legacy and WithConcurrency must not modify", param.Name())
+	}
+}
+
+func TestImmutableParameters(t *testing.T) {
+
+	typ := reflect.TypeOf(frodo.Parameters{})
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.PkgPath == "" {
+			t.Error("frodo_test.go/TestImmutableParameters: field", field.Name, "of a shared set can be modified")
+		}
+	}
+
+	param := frodo.Frodo640()
+	pk, sk := param.EncapsKeyGen()
+	param.ErrorTable()[0] = 1
+	if X := frodo.Frodo640().ErrorTable(); X[0] != 4643 || frodo.Frodo640().ModulusBits() != 15 {
+		t.Error("frodo_test.go/TestImmutableParameters: a value returned by Frodo640 modifies the set")
+	}
+	ct, ss := frodo.Frodo640().Encaps(pk)
+	if !bytes.Equal(frodo.Frodo640().Decaps(ct, sk), ss) {
+		t.Error("frodo_test.go/TestImmutableParameters: keys of Frodo640 broke after its error table was written")
+	}
+}
+
+// testing concurrent use of shared parameter sets, keys and ciphertexts,
+// run with -race
+
+func TestConcurrentUse(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.FrodoKEM640SHAKE(), frodo.Frodo640AES().WithConcurrency(2)} {
+		pk, sk := param.EncapsKeyGen()
+		ct, ss := param.Encaps(pk)
+		ppk, psk := param.KeyGen()
+
+		var wg sync.WaitGroup
+		for g := 0; g < 16; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 4; i++ {
+					switch (g + i) % 4 {
+					case 0:
+						pk1, sk1 := param.EncapsKeyGen()
+						ct1, ss1 := param.Encaps(pk1)
+						if !bytes.Equal(param.Decaps(ct1, sk1), ss1) {
+							t.Errorf("frodo_test.go/TestConcurrentUse: %s fresh keys decapsulate a different secret", param.Name())
+						}
+					case 1:
+						ct1, ss1 := param.Encaps(pk)
+						if !bytes.Equal(param.Decaps(ct1, sk), ss1) {
+							t.Errorf("frodo_test.go/TestConcurrentUse: %s shared keys decapsulate a different secret", param.Name())
+						}
+					case 2:
+						if !bytes.Equal(param.Decaps(ct, sk), ss) {
+							t.Errorf("frodo_test.go/TestConcurrentUse: %s shared ciphertext decapsulates a different secret", param.Name())
+						}
+					case 3:
+						message := make([]byte, param.SharedKeySize())
+						message[0] = byte(g)
+						if !bytes.Equal(param.Dec(param.Enc(message, ppk), psk), message) {
+							t.Errorf("frodo_test.go/TestConcurrentUse: %s decrypts a different message", param.Name())
+						}
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	}
+}
+
+func TestPreparedKeys(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.FrodoKEM640AES(), frodo.EFrodoKEM976SHAKE()} {
+		pk, sk := param.EncapsKeyGen()
+		ppk, err := param.PreparePublicKey(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		psk, err := param.PrepareSecretKey(sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ppk.Pkh(), sk.Pkh) || !bytes.Equal(ppk.PublicKey().Bytes(), pk.Bytes()) {
+			t.Errorf("frodo_test.go/TestPreparedKeys: %s prepared public key differs from pk", param.Name())
+		}
+
+		coins := make([]byte, param.EncapsulationSeedSize())
+		rand.Read(coins)
+		ct, ss, _ := ppk.EncapsFrom(bytes.NewReader(coins))
+		ct1, ss1, _ := param.EncapsFrom(bytes.NewReader(coins), pk)
+		b, _ := ct.MarshalBinary()
+		b1, _ := ct1.MarshalBinary()
+		if !bytes.Equal(b, b1) || !bytes.Equal(ss, ss1) {
+			t.Errorf("frodo_test.go/TestPreparedKeys: %s prepared Encaps differs from Encaps", param.Name())
+		}
+
+		done := make(chan bool)
+		for g := 0; g < 4; g++ {
+			go func() {
+				ct, ss := ppk.Encaps()
+				ss1, err := psk.DecapsChecked(ct)
+				ss2, _ := psk.Decapsulate(b)
+				done <- err == nil && bytes.Equal(ss, ss1) && bytes.Equal(ss2, param.Decaps(ct1, sk))
+			}()
+		}
+		for g := 0; g < 4; g++ {
+			if !<-done {
+				t.Errorf("frodo_test.go/TestPreparedKeys: %s prepared keys disagree on the secret", param.Name())
+			}
+		}
+	}
+
+	param := frodo.Frodo640()
+	if _, err := param.PreparePublicKey(&frodo.EncapsPublicKey{}); !errors.Is(err, frodo.ErrInvalidPublicKey) {
+		t.Errorf("frodo_test.go/TestPreparedKeys: expected ErrInvalidPublicKey, got %v", err)
+	}
+	_, sk := param.EncapsKeyGen()
+	if _, err := frodo.Frodo976().PrepareSecretKey(sk); !errors.Is(err, frodo.ErrParameterMismatch) {
+		t.Errorf("frodo_test.go/TestPreparedKeys: expected ErrParameterMismatch, got %v", err)
+	}
+}
+
+func TestKeyCache(t *testing.T) {
+
+	param := frodo.Frodo640()
+	cache := param.NewKeyCache(2)
+	pk1, _ := param.EncapsKeyGen()
+	pk2, _ := param.EncapsKeyGen()
+	pk3, _ := param.EncapsKeyGen()
+
+	p1, _ := cache.Get(pk1)
+	cache.Get(pk2)
+	if p, _ := cache.Get(pk1); p != p1 { // pk1 is the most recently used
+		t.Error("frodo_test.go/TestKeyCache: expected a cache hit")
+	}
+	cache.Get(pk3) // evicts pk2
+	if cache.Len() != 2 {
+		t.Errorf("frodo_test.go/TestKeyCache: expected 2 keys, got %d", cache.Len())
+	}
+	if p, _ := cache.Get(pk1); p != p1 {
+		t.Error("frodo_test.go/TestKeyCache: pk1 is evicted instead of pk2")
+	}
+	if _, err := cache.Get(&frodo.EncapsPublicKey{SeedA: pk1.SeedA}); !errors.Is(err, frodo.ErrInvalidPublicKey) {
+		t.Errorf("frodo_test.go/TestKeyCache: expected ErrInvalidPublicKey, got %v", err)
+	}
+}
+
+func TestEncapsulateBatch(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.FrodoKEM976AES().WithConcurrency(3)} {
+		pk, sk := param.EncapsKeyGen()
+		coins := make([]byte, 5*param.EncapsulationSeedSize())
+		rand.Read(coins)
+
+		cts, sss, err := param.EncapsBatchFrom(bytes.NewReader(coins), pk, 5)
+		if err != nil || len(cts) != 5 || len(sss) != 5 {
+			t.Fatalf("frodo_test.go/TestEncapsulateBatch: %s got %d pairs, %v", param.Name(), len(cts), err)
+		}
+		for i := range cts {
+			mu := coins[i*param.EncapsulationSeedSize() : (i+1)*param.EncapsulationSeedSize()]
+			ct, ss, _ := param.EncapsulateDeterministic(pk, mu)
+			b, _ := cts[i].MarshalBinary()
+			if !bytes.Equal(b, ct) || !bytes.Equal(sss[i], ss) || !bytes.Equal(param.Decaps(cts[i], sk), ss) {
+				t.Errorf("frodo_test.go/TestEncapsulateBatch: %s encapsulation %d differs from a single one", param.Name(), i)
+			}
+		}
+
+		bcts, bsss, err := param.EncapsulateBatch(pk, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range bcts {
+			if ss, err := param.Decapsulate(sk, bcts[i]); err != nil || !bytes.Equal(ss, bsss[i]) {
+				t.Errorf("frodo_test.go/TestEncapsulateBatch: %s batch %d decapsulates a different secret", param.Name(), i)
+			}
+		}
+		if bytes.Equal(bsss[0], bsss[1]) {
+			t.Errorf("frodo_test.go/TestEncapsulateBatch: %s batch secrets are not independent", param.Name())
+		}
+		if _, _, err := param.EncapsulateBatch(pk, 0); err == nil {
+			t.Errorf("frodo_test.go/TestEncapsulateBatch: %s expected an error for an empty batch", param.Name())
+		}
+	}
+}
+
+func TestEncapsulator(t *testing.T) {
+
+	param := frodo.FrodoKEM640SHAKE()
+	pk, sk := param.EncapsKeyGen()
+	e, err := param.NewEncapsulator(pk, 4)
+	if err != nil {
+		t.Fatal("frodo_test.go/TestEncapsulator:", err)
+	}
+
+	seen, results := make(map[string]bool), make(chan []byte, 12)
+	for g := 0; g < 3; g++ {
+		go func() {
+			for i := 0; i < 4; i++ {
+				ct, ss, err := e.Encaps()
+				if err != nil || !bytes.Equal(param.Decaps(ct, sk), ss) {
+					results <- nil
+					continue
+				}
+				results <- ss
+			}
+		}()
+	}
+	for i := 0; i < 12; i++ {
+		ss := <-results
+		if ss == nil {
+			t.Fatal("frodo_test.go/TestEncapsulator: precomputed encapsulation does not decapsulate")
+		}
+		if seen[string(ss)] {
+			t.Fatal("frodo_test.go/TestEncapsulator: an encapsulation is handed out twice")
+		}
+		seen[string(ss)] = true
+	}
+
+	e.Close()
+	e.Close()
+	if _, _, err := e.Encaps(); !errors.Is(err, frodo.ErrClosed) {
+		t.Errorf("frodo_test.go/TestEncapsulator: expected ErrClosed, got %v", err)
+	}
+	if e.Len() != 0 {
+		t.Errorf("frodo_test.go/TestEncapsulator: %d encapsulations left after Close", e.Len())
+	}
+	if _, err := param.NewEncapsulator(&frodo.EncapsPublicKey{}, 4); !errors.Is(err, frodo.ErrInvalidPublicKey) {
+		t.Errorf("frodo_test.go/TestEncapsulator: expected ErrInvalidPublicKey, got %v", err)
+	}
+}
+
+func TestScratchAllocs(t *testing.T) {
+
+	for _, param := range []*frodo.Parameters{frodo.Frodo640(), frodo.Frodo1344AES(), frodo.FrodoKEM976SHAKE(), frodo.EFrodoKEM640AES()} {
+		pk, sk := param.EncapsKeyGen()
+		s := param.NewScratch()
+		ct, ss := make([]byte, 0, param.CiphertextSize()), make([]byte, 0, param.SharedKeySize())
+		ss2 := make([]byte, 0, param.SharedKeySize())
+
+		handshake := func() {
+			var err error
+			if ct, ss, err = s.EncapsulateTo(ct[:0], ss[:0], pk); err != nil {
+				t.Fatal("frodo_test.go/TestScratchAllocs:", err)
+			}
+			if ss2, err = s.DecapsulateTo(ss2[:0], sk, ct); err != nil {
+				t.Fatal("frodo_test.go/TestScratchAllocs:", err)
+			}
+		}
+		handshake()
+		c, err := param.UnmarshalEncapsCipherText(ct)
+		if err != nil || !bytes.Equal(ss, ss2) || !bytes.Equal(param.Decaps(c, sk), ss) {
+			t.Errorf("frodo_test.go/TestScratchAllocs: %s scratch handshake disagrees on the secret", param.Name())
+		}
+		if allocs := testing.AllocsPerRun(5, handshake); allocs != 0 {
+			t.Errorf("frodo_test.go/TestScratchAllocs: %s handshake allocates %v times", param.Name(), allocs)
+		}
+	}
+}
+
+func TestAppendVariants(t *testing.T) {
+
+	param := frodo.Frodo976()
+	r := make([]byte, 2*976*8)
+	rand.Read(r)
+	C := param.SampleMatrix(r, 976, 8)
+	k := make([]byte, 24)
+	rand.Read(k)
+
+	packed, decoded := make([]byte, 0, 2*len(r)), make([]byte, 0, 64)
+	dst := make([][]uint16, 976)
+	for i := range dst {
+		dst[i] = make([]uint16, 8)
+	}
+	K := [][]uint16{make([]uint16, 8), make([]uint16, 8), make([]uint16, 8), make([]uint16, 8),
+		make([]uint16, 8), make([]uint16, 8), make([]uint16, 8), make([]uint16, 8)}
+
+	check := func(name string, f func()) {
+		if allocs := testing.AllocsPerRun(3, f); allocs != 0 {
+			t.Errorf("frodo_test.go/TestAppendVariants: %s allocates %v times", name, allocs)
+		}
+	}
+	check("PackTo", func() { packed = param.PackTo(append(packed[:0], 7), C) })
+	if packed[0] != 7 || !bytes.Equal(packed[1:], param.Pack(C)) {
+		t.Error("frodo_test.go/TestAppendVariants: PackTo differs from Pack")
+	}
+	check("UnpackTo", func() { param.UnpackTo(dst, packed[1:]) })
+	check("SampleMatrixTo", func() { param.SampleMatrixTo(dst, r) })
+	if !bytes.Equal(param.Pack(dst), param.Pack(C)) {
+		t.Error("frodo_test.go/TestAppendVariants: SampleMatrixTo differs from SampleMatrix")
+	}
+	check("EncodeTo", func() { param.EncodeTo(K, k) })
+	check("DecodeTo", func() { decoded = param.DecodeTo(decoded[:0], K) })
+	if !bytes.Equal(param.Pack(K), param.Pack(param.Encode(k))) || !bytes.Equal(decoded, k) {
+		t.Error("frodo_test.go/TestAppendVariants: EncodeTo or DecodeTo differs from Encode and Decode")
+	}
+}
+
+// matrixOf returns the n1-by-n2 matrix of the entries c, row by row
+func matrixOf(c []uint16, n1, n2 int) [][]uint16 {
+
+	C := make([][]uint16, n1)
+	for i := range C {
+		C[i] = c[i*n2 : (i+1)*n2]
+	}
+	return C
+}
+
+func TestUnalignedRows(t *testing.T) {
+
+	param := frodo.Frodo640() // D = 15, rows of 3 or 5 entries end inside a byte
+	for _, dims := range [][2]int{{8, 3}, {3, 5}, {1, 8}} {
+		n1, n2 := dims[0], dims[1]
+		c := make([]uint16, n1*n2)
+		for i := range c {
+			c[i] = uint16(rand.Intn(1 << 15))
+		}
+		C := matrixOf(c, n1, n2)
+		whole := param.Pack([][]uint16{c})
+		if packed := param.Pack(C); len(packed) != (15*n1*n2+7)/8 || !bytes.Equal(packed, whole) {
+			t.Fatalf("frodo_test.go/TestUnalignedRows: packing of the %d-by-%d matrix differs from one row of its entries", n1, n2)
+		}
+		unpacked, dst := param.Unpack(whole, n1, n2), matrixOf(make([]uint16, n1*n2), n1, n2)
+		param.UnpackTo(dst, whole)
+		if !reflect.DeepEqual(unpacked, C) || !reflect.DeepEqual(dst, C) {
+			t.Fatalf("frodo_test.go/TestUnalignedRows: unpacking of the %d-by-%d matrix differs from the matrix", n1, n2)
+		}
+	}
+
+	param = frodo.Frodo976() // B = 3, rows of 4 entries are 12 bits
+	k := make([]byte, 24)
+	rand.Read(k)
+	K := matrixOf(make([]uint16, 64), 16, 4)
+	param.EncodeTo(K, k)
+	if !bytes.Equal(param.Pack(K), param.Pack(param.Encode(k))) || !bytes.Equal(param.DecodeTo(nil, K), k) {
+		t.Error("frodo_test.go/TestUnalignedRows: encoding of rows of 12 bits differs from Encode and Decode")
+	}
+}
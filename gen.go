@@ -0,0 +1,162 @@
+package frodo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+
+	"github.com/mariiatuzovska/frodo/internal/keccak"
+	"golang.org/x/crypto/sha3"
+)
+
+// generators of the pseudorandom matrix A
+const (
+	genSHAKE128 = iota // SHAKE128 row expansion [FKEM] Algorithm 8
+	genAES128          // AES128 row expansion [FKEM] Algorithm 7
+	genLegacy          // derivation of releases before the spec-conformant Gen
+)
+
+// rowsGenerator expands seedA into rows of the n-by-n matrix A
+type rowsGenerator interface {
+	// rows fills dst with the rows i, i+1, ... of A, len(dst) is a multiple of n
+	rows(dst []uint16, i int)
+}
+
+// newRows returns the rows generator of A for seedA
+func (param *Parameters) newRows(seedA []byte) rowsGenerator {
+
+	switch param.gen {
+	case genAES128:
+		return newAESRows(param, seedA)
+	case genLegacy:
+		return &legacyRows{param: param, seed: seedA}
+	default:
+		return newShakeRows(param, seedA)
+	}
+}
+
+// shakeRows generates row i of A as SHAKE128(<i> || seedA, 16n),
+// <i> is the 16-bit little-endian row index, entries are 16-bit little-endian.
+// Rows are expanded four at a time by the 4-way Keccak like in the reference implementation
+type shakeRows struct {
+	param *Parameters
+	h     sha3.ShakeHash
+	in    [4][]byte // <i+k> || seedA
+	out   [4][]byte // 2n bytes of SHAKE128 output of row i+k
+}
+
+func newShakeRows(param *Parameters, seedA []byte) *shakeRows {
+
+	r := &shakeRows{param: param, h: sha3.NewShake128()}
+	for k := range r.in {
+		r.in[k], r.out[k] = make([]byte, 2, 2+len(seedA)), make([]byte, 2*param.no)
+	}
+	r.reset(seedA)
+	return r
+}
+
+// reset makes r generate the rows of A for seedA
+func (r *shakeRows) reset(seedA []byte) {
+
+	for k := range r.in {
+		r.in[k] = append(r.in[k][:2], seedA...)
+	}
+}
+
+func (r *shakeRows) rows(dst []uint16, i int) {
+
+	no := r.param.no
+	for ; len(dst) >= 4*no; dst, i = dst[4*no:], i+4 {
+		for k := range r.in {
+			r.in[k][0], r.in[k][1] = byte(i+k), byte((i+k)>>8)
+		}
+		keccak.Shake128x4(&r.out, &r.in)
+		for k := range r.out {
+			r.param.entries(dst[k*no:(k+1)*no], r.out[k])
+		}
+	}
+	for ; len(dst) > 0; dst, i = dst[no:], i+1 {
+		r.in[0][0], r.in[0][1] = byte(i), byte(i>>8)
+		r.h.Reset()
+		r.h.Write(r.in[0])
+		r.h.Read(r.out[0])
+		r.param.entries(dst[:no], r.out[0])
+	}
+}
+
+// entries sets row to the 16-bit little-endian entries of b reduced modulo q
+func (param *Parameters) entries(row []uint16, b []byte) {
+
+	if param.kern != nil {
+		param.kern.entries(row, b)
+		return
+	}
+	for j := range row {
+		row[j] = (uint16(b[2*j]) | uint16(b[2*j+1])<<8) & param.q
+	}
+}
+
+// aesRows generates the entries (i, j..j+7) of A as AES128(seedA, <i> || <j> || 0^96),
+// <i>, <j> and the entries are 16-bit little-endian, j is a multiple of 8
+type aesRows struct {
+	param *Parameters
+	key   []byte // seedA
+	block cipher.Block
+	in    []byte // 16·n/8 blocks <i> || <j> || 0^96 of one row
+	out   []byte // 2n bytes of AES128 output
+}
+
+func newAESRows(param *Parameters, seedA []byte) *aesRows {
+
+	r := &aesRows{param: param, in: make([]byte, 2*param.no), out: make([]byte, 2*param.no)}
+	for j := 0; j < param.no; j += 8 {
+		r.in[2*j+2], r.in[2*j+3] = byte(j), byte(j>>8)
+	}
+	r.reset(seedA)
+	return r
+}
+
+// reset makes r generate the rows of A for seedA, the key schedule is kept if seedA is unchanged
+func (r *aesRows) reset(seedA []byte) {
+
+	if r.block != nil && bytes.Equal(r.key, seedA) {
+		return
+	}
+	block, err := aes.NewCipher(seedA)
+	if err != nil {
+		panic("frodo: AES128 key must be 16 bytes") // lseedA is 16 for every parameter set
+	}
+	r.key, r.block = append(r.key[:0], seedA...), block
+}
+
+func (r *aesRows) rows(dst []uint16, i int) {
+
+	for k := 0; k < len(dst); k, i = k+r.param.no, i+1 {
+		for j := 0; j < len(r.in); j += aes.BlockSize {
+			r.in[j], r.in[j+1] = byte(i), byte(i>>8)
+			r.block.Encrypt(r.out[j:j+aes.BlockSize], r.in[j:j+aes.BlockSize])
+		}
+		r.param.entries(dst[k:k+r.param.no], r.out)
+	}
+}
+
+// legacyRows reproduces the derivation of A used before the spec-conformant Gen:
+// big-endian row index, param.shake (SHAKE256 for 976 and 1344), and the low byte of
+// the entry (i, j) taken from the row index i instead of the column index j
+type legacyRows struct {
+	param *Parameters
+	seed  []byte
+}
+
+func (r *legacyRows) rows(dst []uint16, i int) {
+
+	for k := 0; k < len(dst); k, i = k+r.param.no, i+1 {
+		b := []byte{byte(i >> 8), byte(i)}
+		b = append(b, r.seed...)
+		shakeStr := r.param.shake(b, r.param.no*2)
+		row := dst[k : k+r.param.no]
+		for j := range row {
+			row[j] = ((uint16(shakeStr[j*2]) << 8) | uint16(shakeStr[i*2+1])) & r.param.q
+		}
+	}
+}
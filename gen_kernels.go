@@ -0,0 +1,273 @@
+//go:build ignore
+
+// gen_kernels emits kernels_gen.go and kernels_noasm_gen.go, the kernels of kernels.go
+// specialized for the dimensions, modulus and error table of every parameter set of [FKEM]:
+//
+//	go generate
+//
+// The sets below must match Frodo640, Frodo976 and Frodo1344 of frodo.go,
+// TestKernels checks the generated code against the generic one
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+)
+
+type set struct {
+	no int      // n
+	D  int      // q = 2^D
+	X  []uint16 // error table, the last entry is 2^15 - 1
+}
+
+var sets = []set{
+	{no: 640, D: 15, X: []uint16{4643, 13363, 20579, 25843, 29227, 31145, 32103, 32525, 32689, 32745, 32762, 32766, 32767}},
+	{no: 976, D: 16, X: []uint16{5638, 15915, 23689, 28571, 31116, 32217, 32613, 32731, 32760, 32766, 32767}},
+	{no: 1344, D: 16, X: []uint16{9142, 23462, 30338, 32361, 32725, 32765, 32767}},
+}
+
+// nbar is m = n of every set, the products are unrolled over it
+const nbar = 8
+
+func main() {
+
+	var b, p bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen_kernels.go; DO NOT EDIT.\n\npackage frodo\n")
+	fmt.Fprintf(&p, "// Code generated by gen_kernels.go; DO NOT EDIT.\n\n//go:build !amd64 || purego\n\npackage frodo\n")
+
+	packed := map[int]bool{}
+	for _, s := range sets {
+		if !packed[s.D] {
+			packed[s.D] = true
+			pack(&b, s.D)
+			unpack(&b, s.D)
+		}
+	}
+	p.WriteString("\n// the products are specialized only without the assembly dot and axpy, which are faster\nfunc init() {\n")
+	for _, s := range sets {
+		q := uint32(1)<<uint(s.D) - 1
+		fmt.Fprintf(&b, "\n// kernels%d are the kernels of the sets with n = %d\n", s.no, s.no)
+		fmt.Fprintf(&b, "var kernels%d = kernels{entries: entries%d, pack: pack%d, unpack: unpack%d, sample: sample%d}\n",
+			s.no, s.no, s.D, s.D, s.no)
+		entries(&b, s, q)
+		sample(&b, s, q)
+		fmt.Fprintf(&p, "\tkernels%[1]d.rowAS, kernels%[1]d.rowSA = rowAS%[1]d, rowSA%[1]d\n", s.no)
+	}
+	p.WriteString("}\n")
+	for _, s := range sets {
+		products(&p, s)
+	}
+
+	write("kernels_gen.go", &b)
+	write("kernels_noasm_gen.go", &p)
+}
+
+func write(name string, b *bytes.Buffer) {
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// entries emits the conversion of 2n bytes of Gen output into a row of A
+func entries(b *bytes.Buffer, s set, q uint32) {
+
+	fmt.Fprintf(b, `
+// entries%[1]d sets the %[1]d entries of row to the 16-bit little-endian words of b reduced modulo q
+func entries%[1]d(row []uint16, b []byte) {
+
+	r, p := (*[%[1]d]uint16)(row), (*[%[2]d]byte)(b)
+	for j := range r {
+		r[j] = (uint16(p[2*j]) | uint16(p[2*j+1])<<8) & %#04[3]x
+	}
+}
+`, s.no, 2*s.no, q)
+}
+
+// pack emits the packing of 8 entries into D bytes, most significant bit first:
+// entry i ends at bit D·(i+1) of the stream and byte j at bit 8·(j+1)
+func pack(b *bytes.Buffer, D int) {
+
+	fmt.Fprintf(b, `
+// pack%[1]d packs the low %[1]d bits of the entries c into b, 8 entries into %[1]d bytes, len(c) is a multiple of 8
+func pack%[1]d(b []byte, c []uint16) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[%[1]d]byte)(b)
+`, D)
+	mask := uint32(1)<<uint(D) - 1
+	for j := 0; j < D; j++ {
+		var terms []string
+		for i := 0; i < 8; i++ {
+			start, end := D*i, D*(i+1)
+			if end <= 8*j || start >= 8*(j+1) {
+				continue
+			}
+			e := fmt.Sprintf("e[%d]", i)
+			switch shift := end - 8*(j+1); {
+			case shift > 0:
+				if D < 16 { // the bits above D would reach the byte
+					e = fmt.Sprintf("(e[%d] & %#04x)", i, mask)
+				}
+				terms = append(terms, fmt.Sprintf("byte(%s>>%d)", e, shift))
+			case shift < 0:
+				terms = append(terms, fmt.Sprintf("byte(%s<<%d)", e, -shift))
+			default:
+				terms = append(terms, fmt.Sprintf("byte(%s)", e))
+			}
+		}
+		fmt.Fprintf(b, "\t\td[%d] = %s\n", j, join(terms))
+	}
+	fmt.Fprintf(b, "\t\tb, c = b[%d:], c[8:]\n\t}\n}\n", D)
+}
+
+// unpack emits the inverse of pack
+func unpack(b *bytes.Buffer, D int) {
+
+	fmt.Fprintf(b, `
+// unpack%[1]d unpacks the %[1]d·len(c) bits of b into the entries c, %[1]d bytes into 8 entries, len(c) is a multiple of 8
+func unpack%[1]d(c []uint16, b []byte) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[%[1]d]byte)(b)
+`, D)
+	mask := uint32(1)<<uint(D) - 1
+	for i := 0; i < 8; i++ {
+		var terms []string
+		start, end := D*i, D*(i+1)
+		for j := 0; j < D; j++ {
+			if end <= 8*j || start >= 8*(j+1) {
+				continue
+			}
+			switch shift := end - 8*(j+1); {
+			case shift > 0:
+				terms = append(terms, fmt.Sprintf("uint16(d[%d])<<%d", j, shift))
+			case shift < 0:
+				terms = append(terms, fmt.Sprintf("uint16(d[%d])>>%d", j, -shift))
+			default:
+				terms = append(terms, fmt.Sprintf("uint16(d[%d])", j))
+			}
+		}
+		expr := join(terms)
+		if D < 16 {
+			expr = fmt.Sprintf("(%s) & %#04x", expr, mask)
+		}
+		fmt.Fprintf(b, "\t\te[%d] = %s\n", i, expr)
+	}
+	fmt.Fprintf(b, "\t\tb, c = b[%d:], c[8:]\n\t}\n}\n", D)
+}
+
+// sample emits the constant-time CDT sampler with the comparisons against the table unrolled
+func sample(b *bytes.Buffer, s set, q uint32) {
+
+	fmt.Fprintf(b, `
+// sample%[1]d samples the entries e from the 16-bit little-endian words of r, see Sample
+func sample%[1]d(e []uint16, r []byte) {
+
+	r = r[:2*len(e)]
+	for i := range e {
+		w := uint16(r[2*i]) | uint16(r[2*i+1])<<8
+		t, sign := w>>1, w&1
+		sum := `, s.no)
+	for z, x := range s.X[:len(s.X)-1] {
+		if z > 0 {
+			b.WriteString(" +\n\t\t\t")
+		}
+		fmt.Fprintf(b, "(%d-t)>>15", x)
+	}
+	fmt.Fprintf(b, `
+		e[i] = ((-sign ^ sum) + sign) & %#04x
+	}
+}
+`, q)
+}
+
+// products emits one row of the streaming products A·S and S·A with the nbar rows of S and Sᵀ unrolled,
+// a row of A is loaded once for all of them
+func products(b *bytes.Buffer, s set) {
+
+	rows := func(name string) {
+		for k := 0; k < nbar; k++ {
+			if k > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "%s%d", name, k)
+		}
+	}
+	slices := func(name string) {
+		for k := 0; k < nbar; k++ {
+			if k > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "%s[%d:%d]", name, k*s.no, (k+1)*s.no)
+		}
+	}
+
+	fmt.Fprintf(b, `
+// rowAS%[1]d sets the %[2]d entries c of a row of A·S to the dot products of the row a of A
+// with the rows of Sᵀ, st holds the %[2]d rows of %[1]d entries
+func rowAS%[1]d(c, a, st []uint16) {
+
+	c, a, st = c[:%[2]d], a[:%[1]d], st[:%[3]d]
+	`, s.no, nbar, nbar*s.no)
+	rows("s")
+	b.WriteString(" := ")
+	slices("st")
+	b.WriteString("\n\tvar ")
+	rows("c")
+	b.WriteString(" uint16\n\tfor j, x := range a {\n")
+	for k := 0; k < nbar; k++ {
+		fmt.Fprintf(b, "\t\tc%d += s%d[j] * x\n", k, k)
+	}
+	b.WriteString("\t}\n\t")
+	for k := 0; k < nbar; k++ {
+		if k > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "c[%d]", k)
+	}
+	b.WriteString(" = ")
+	rows("c")
+	fmt.Fprintf(b, `
+}
+
+// rowSA%[1]d adds s[k·%[1]d+i]·a to the row k of p for the %[2]d rows of p and s of %[1]d entries,
+// a is the row i of A
+func rowSA%[1]d(p, s []uint16, i int, a []uint16) {
+
+	p, s, a = p[:%[3]d], s[:%[3]d], a[:%[1]d]
+	`, s.no, nbar, nbar*s.no)
+	rows("p")
+	b.WriteString(" := ")
+	slices("p")
+	b.WriteString("\n\t")
+	rows("s")
+	b.WriteString(" := ")
+	for k := 0; k < nbar; k++ {
+		if k > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "s[%d+i]", k*s.no)
+	}
+	b.WriteString("\n\tfor j, x := range a {\n")
+	for k := 0; k < nbar; k++ {
+		fmt.Fprintf(b, "\t\tp%d[j] += s%d * x\n", k, k)
+	}
+	b.WriteString("\t}\n}\n")
+}
+
+func join(terms []string) string {
+
+	expr := terms[0]
+	for _, t := range terms[1:] {
+		expr += " | " + t
+	}
+	return expr
+}
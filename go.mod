@@ -1,5 +1,8 @@
 module github.com/mariiatuzovska/frodo
 
-go 1.15
+go 1.23.0
 
-require golang.org/x/crypto v0.35.0
+require (
+	golang.org/x/crypto v0.35.0
+	golang.org/x/sys v0.30.0
+)
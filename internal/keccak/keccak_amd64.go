@@ -0,0 +1,20 @@
+//go:build amd64 && !purego
+
+package keccak
+
+import "golang.org/x/sys/cpu"
+
+// useAVX2 selects the parallel permutation of keccak_amd64.s
+var useAVX2 = cpu.X86.HasAVX2
+
+func permute4(a *State4) {
+
+	if useAVX2 {
+		permute4AVX2(a, &roundConstants)
+		return
+	}
+	permute4Generic(a)
+}
+
+//go:noescape
+func permute4AVX2(a *State4, rc *[24]uint64)
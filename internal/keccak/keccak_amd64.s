@@ -0,0 +1,150 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// The four states are permuted in parallel, lane i of the State4 is one YMM
+// register wide and holds lane i of every instance. Y0-Y4 hold the column
+// parities C, Y5-Y9 the θ effects D, and the lanes after ρ and π are kept in
+// a 25·32-byte B on the stack until χ writes them back to the state.
+
+// COLUMN sets C to the parity of column x
+#define COLUMN(x, C) \
+	VMOVDQU (x*32)(DI), C; \
+	VPXOR   ((x+5)*32)(DI), C, C; \
+	VPXOR   ((x+10)*32)(DI), C, C; \
+	VPXOR   ((x+15)*32)(DI), C, C; \
+	VPXOR   ((x+20)*32)(DI), C, C
+
+// EFFECT sets D to L ^ rol(R, 1)
+#define EFFECT(L, R, D) \
+	VPSLLQ $1, R, Y10; \
+	VPSRLQ $63, R, Y11; \
+	VPOR   Y10, Y11, Y11; \
+	VPXOR  L, Y11, D
+
+// LANE stores rol(lane i ^ D, r) to lane j of B
+#define LANE(i, D, r, j) \
+	VMOVDQU (i*32)(DI), Y10; \
+	VPXOR   D, Y10, Y10; \
+	VPSLLQ  $r, Y10, Y11; \
+	VPSRLQ  $(64-r), Y10, Y10; \
+	VPOR    Y11, Y10, Y10; \
+	VMOVDQU Y10, (j*32)(SP)
+
+// CHI sets lane j of the state to B0 ^ (^B1 & B2)
+#define CHI(B0, B1, B2, j) \
+	VPANDN  B2, B1, Y10; \
+	VPXOR   B0, Y10, Y10; \
+	VMOVDQU Y10, (j*32)(DI)
+
+// func permute4AVX2(a *State4, rc *[24]uint64)
+TEXT ·permute4AVX2(SB), 0, $800-16
+	MOVQ a+0(FP), DI
+	MOVQ rc+8(FP), R8
+	MOVQ $24, CX
+
+round:
+	// θ
+	COLUMN(0, Y0)
+	COLUMN(1, Y1)
+	COLUMN(2, Y2)
+	COLUMN(3, Y3)
+	COLUMN(4, Y4)
+	EFFECT(Y4, Y1, Y5)
+	EFFECT(Y0, Y2, Y6)
+	EFFECT(Y1, Y3, Y7)
+	EFFECT(Y2, Y4, Y8)
+	EFFECT(Y3, Y0, Y9)
+
+	// θ, ρ and π, lane x + 5y moves to (y, 2x + 3y)
+	VMOVDQU 0(DI), Y10
+	VPXOR   Y5, Y10, Y10
+	VMOVDQU Y10, 0(SP)
+	LANE(6, Y6, 44, 1)
+	LANE(12, Y7, 43, 2)
+	LANE(18, Y8, 21, 3)
+	LANE(24, Y9, 14, 4)
+	LANE(3, Y8, 28, 5)
+	LANE(9, Y9, 20, 6)
+	LANE(10, Y5, 3, 7)
+	LANE(16, Y6, 45, 8)
+	LANE(22, Y7, 61, 9)
+	LANE(1, Y6, 1, 10)
+	LANE(7, Y7, 6, 11)
+	LANE(13, Y8, 25, 12)
+	LANE(19, Y9, 8, 13)
+	LANE(20, Y5, 18, 14)
+	LANE(4, Y9, 27, 15)
+	LANE(5, Y5, 36, 16)
+	LANE(11, Y6, 10, 17)
+	LANE(17, Y7, 15, 18)
+	LANE(23, Y8, 56, 19)
+	LANE(2, Y7, 62, 20)
+	LANE(8, Y8, 55, 21)
+	LANE(14, Y9, 39, 22)
+	LANE(15, Y5, 41, 23)
+	LANE(21, Y6, 2, 24)
+
+	// χ
+	VMOVDQU (0*32)(SP), Y0
+	VMOVDQU (1*32)(SP), Y1
+	VMOVDQU (2*32)(SP), Y2
+	VMOVDQU (3*32)(SP), Y3
+	VMOVDQU (4*32)(SP), Y4
+	CHI(Y0, Y1, Y2, 0)
+	CHI(Y1, Y2, Y3, 1)
+	CHI(Y2, Y3, Y4, 2)
+	CHI(Y3, Y4, Y0, 3)
+	CHI(Y4, Y0, Y1, 4)
+	VMOVDQU (5*32)(SP), Y0
+	VMOVDQU (6*32)(SP), Y1
+	VMOVDQU (7*32)(SP), Y2
+	VMOVDQU (8*32)(SP), Y3
+	VMOVDQU (9*32)(SP), Y4
+	CHI(Y0, Y1, Y2, 5)
+	CHI(Y1, Y2, Y3, 6)
+	CHI(Y2, Y3, Y4, 7)
+	CHI(Y3, Y4, Y0, 8)
+	CHI(Y4, Y0, Y1, 9)
+	VMOVDQU (10*32)(SP), Y0
+	VMOVDQU (11*32)(SP), Y1
+	VMOVDQU (12*32)(SP), Y2
+	VMOVDQU (13*32)(SP), Y3
+	VMOVDQU (14*32)(SP), Y4
+	CHI(Y0, Y1, Y2, 10)
+	CHI(Y1, Y2, Y3, 11)
+	CHI(Y2, Y3, Y4, 12)
+	CHI(Y3, Y4, Y0, 13)
+	CHI(Y4, Y0, Y1, 14)
+	VMOVDQU (15*32)(SP), Y0
+	VMOVDQU (16*32)(SP), Y1
+	VMOVDQU (17*32)(SP), Y2
+	VMOVDQU (18*32)(SP), Y3
+	VMOVDQU (19*32)(SP), Y4
+	CHI(Y0, Y1, Y2, 15)
+	CHI(Y1, Y2, Y3, 16)
+	CHI(Y2, Y3, Y4, 17)
+	CHI(Y3, Y4, Y0, 18)
+	CHI(Y4, Y0, Y1, 19)
+	VMOVDQU (20*32)(SP), Y0
+	VMOVDQU (21*32)(SP), Y1
+	VMOVDQU (22*32)(SP), Y2
+	VMOVDQU (23*32)(SP), Y3
+	VMOVDQU (24*32)(SP), Y4
+	CHI(Y0, Y1, Y2, 20)
+	CHI(Y1, Y2, Y3, 21)
+	CHI(Y2, Y3, Y4, 22)
+	CHI(Y3, Y4, Y0, 23)
+	CHI(Y4, Y0, Y1, 24)
+
+	// ι
+	VPBROADCASTQ (R8), Y10
+	VPXOR        0(DI), Y10, Y10
+	VMOVDQU      Y10, 0(DI)
+
+	ADDQ $8, R8
+	DECQ CX
+	JNZ  round
+
+	VZEROUPPER
+	RET
@@ -0,0 +1,7 @@
+//go:build !amd64 || purego
+
+package keccak
+
+func permute4(a *State4) {
+	permute4Generic(a)
+}
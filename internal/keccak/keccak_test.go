@@ -0,0 +1,72 @@
+package keccak
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// testing Shake128x4 against golang.org/x/crypto/sha3
+
+func TestShake128x4(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, inLen := range []int{0, 1, 18, 34, 167, 168, 169, 400} {
+		for _, outLen := range []int{1, 32, 168, 169, 1280, 1952, 2688} {
+			var in, out [4][]byte
+			for k := range in {
+				in[k], out[k] = make([]byte, inLen), make([]byte, outLen)
+				rnd.Read(in[k])
+			}
+			Shake128x4(&out, &in)
+			for k := range in {
+				want := make([]byte, outLen)
+				sha3.ShakeSum128(want, in[k])
+				if !bytes.Equal(out[k], want) {
+					t.Fatalf("keccak_test.go/TestShake128x4: instance %d of %d input and %d output bytes differs from SHAKE128", k, inLen, outLen)
+				}
+			}
+		}
+	}
+}
+
+func TestPermute4Generic(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(2))
+	var a State4
+	for i := range a {
+		for k := range a[i] {
+			a[i][k] = rnd.Uint64()
+		}
+	}
+	b := a
+	for i := 0; i < 8; i++ {
+		a.Permute()
+		permute4Generic(&b)
+		if a != b {
+			t.Fatalf("keccak_test.go/TestPermute4Generic: permutation %d differs from the generic one", i)
+		}
+	}
+}
+
+func BenchmarkShake128x4(b *testing.B) {
+
+	var in, out [4][]byte
+	for k := range in {
+		in[k], out[k] = make([]byte, 18), make([]byte, 2688)
+	}
+	b.Run("x4", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Shake128x4(&out, &in)
+		}
+	})
+	b.Run("sha3", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for k := range in {
+				sha3.ShakeSum128(out[k], in[k])
+			}
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// Package nist implements the AES-256 CTR_DRBG randombytes of the NIST PQC
+// submission packages (rng.c), used to reproduce the known-answer tests.
+// It is not a general purpose randomness source.
+package nist
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+// DRBG is the AES-256 CTR_DRBG without derivation function and reseeding,
+// every Read is one call of randombytes
+type DRBG struct {
+	key [32]byte
+	v   [16]byte
+}
+
+// NewDRBG returns the DRBG instantiated by randombytes_init with 48 bytes of
+// entropy and an optional 48-byte personalization string
+func NewDRBG(entropy, personalization []byte) *DRBG {
+
+	var seed [48]byte
+	copy(seed[:], entropy)
+	for i := range personalization {
+		seed[i] ^= personalization[i]
+	}
+
+	d := new(DRBG)
+	d.update(seed[:])
+	return d
+}
+
+// Read fills b with the output of randombytes, it never fails
+func (d *DRBG) Read(b []byte) (int, error) {
+
+	block := d.cipher()
+	var out [16]byte
+	for i := 0; i < len(b); i += 16 {
+		d.increment()
+		block.Encrypt(out[:], d.v[:])
+		copy(b[i:], out[:])
+	}
+	d.update(nil)
+	return len(b), nil
+}
+
+// update is AES256_CTR_DRBG_Update
+func (d *DRBG) update(provided []byte) {
+
+	block := d.cipher()
+	var temp [48]byte
+	for i := 0; i < 3; i++ {
+		d.increment()
+		block.Encrypt(temp[16*i:], d.v[:])
+	}
+	for i := range provided {
+		temp[i] ^= provided[i]
+	}
+	copy(d.key[:], temp[:32])
+	copy(d.v[:], temp[32:])
+}
+
+func (d *DRBG) increment() {
+
+	for j := 15; j >= 0; j-- {
+		d.v[j]++
+		if d.v[j] != 0 {
+			break
+		}
+	}
+}
+
+func (d *DRBG) cipher() cipher.Block {
+
+	block, _ := aes.NewCipher(d.key[:]) // 32-byte key never fails
+	return block
+}
@@ -0,0 +1,25 @@
+package nist
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// the first seed of every PQCgenKAT_kem.c request file,
+// randombytes(48) after randombytes_init(0, 1, ..., 47)
+func TestDRBG(t *testing.T) {
+
+	entropy := make([]byte, 48)
+	for i := range entropy {
+		entropy[i] = byte(i)
+	}
+
+	seed := make([]byte, 48)
+	NewDRBG(entropy, nil).Read(seed)
+
+	const expected = "061550234D158C5EC95595FE04EF7A25767F2E24CC2BC479D09D86DC9ABCFDE7056A8C266F9EF97ED08541DBD2E1FFA1"
+	if got := strings.ToUpper(hex.EncodeToString(seed)); got != expected {
+		t.Error("drbg_test.go/TestDRBG: expected seed", expected, "but has got", got)
+	}
+}
@@ -0,0 +1,262 @@
+package frodo_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mariiatuzovska/frodo"
+	"github.com/mariiatuzovska/frodo/internal/nist"
+)
+
+// testing known answers of the NIST submission package
+// frodo pkg kem.go
+// PQCgenKAT_kem.c seeds the DRBG with every request seed, then runs keypair, enc & dec
+//
+// The expected values come from the submission package only, never from this code:
+// katDigests holds SHA-256 digests of official response files, and official response
+// files copied into testdata are replayed entry by entry
+
+type katEntry struct {
+	count                int
+	seed, pk, sk, ct, ss []byte
+}
+
+// katDigests are SHA-256 digests of the 100 entries of official response files
+var katDigests = []struct {
+	param  *frodo.Parameters
+	header string // first line of the file, the name of the set in the submission
+	digest string
+}{
+	// PQCkemKAT_19888_shake.rsp of https://github.com/microsoft/PQCrypto-LWEKE at
+	// 66fc7744c3aae6acfc5fcc587ec7f2cdec48d216, as published in kem/frodo/kat_test.go of
+	// github.com/cloudflare/circl v1.6.1
+	{frodo.Frodo640(), "FrodoKEM-640-SHAKE", "604a10cfc871dfaed9cb5b057c644ab03b16852cea7f39bc7f9831513b5b1cfa"},
+}
+
+// katFiles are the official response files replayed when they are present in testdata
+var katFiles = []struct {
+	param *frodo.Parameters
+	file  string
+}{
+	{frodo.Frodo640AES(), "PQCkemKAT_19888.rsp"},
+	{frodo.Frodo976AES(), "PQCkemKAT_31296.rsp"},
+	{frodo.Frodo1344AES(), "PQCkemKAT_43088.rsp"},
+	{frodo.Frodo640(), "PQCkemKAT_19888_shake.rsp"},
+	{frodo.Frodo976(), "PQCkemKAT_31296_shake.rsp"},
+	{frodo.Frodo1344(), "PQCkemKAT_43088_shake.rsp"},
+	{frodo.FrodoKEM640AES(), "FrodoKEM/PQCkemKAT_19888.rsp"},
+	{frodo.FrodoKEM976AES(), "FrodoKEM/PQCkemKAT_31296.rsp"},
+	{frodo.FrodoKEM1344AES(), "FrodoKEM/PQCkemKAT_43088.rsp"},
+	{frodo.FrodoKEM640SHAKE(), "FrodoKEM/PQCkemKAT_19888_shake.rsp"},
+	{frodo.FrodoKEM976SHAKE(), "FrodoKEM/PQCkemKAT_31296_shake.rsp"},
+	{frodo.FrodoKEM1344SHAKE(), "FrodoKEM/PQCkemKAT_43088_shake.rsp"},
+	{frodo.EFrodoKEM640AES(), "eFrodoKEM/PQCkemKAT_19888.rsp"},
+	{frodo.EFrodoKEM976AES(), "eFrodoKEM/PQCkemKAT_31296.rsp"},
+	{frodo.EFrodoKEM1344AES(), "eFrodoKEM/PQCkemKAT_43088.rsp"},
+	{frodo.EFrodoKEM640SHAKE(), "eFrodoKEM/PQCkemKAT_19888_shake.rsp"},
+	{frodo.EFrodoKEM976SHAKE(), "eFrodoKEM/PQCkemKAT_31296_shake.rsp"},
+	{frodo.EFrodoKEM1344SHAKE(), "eFrodoKEM/PQCkemKAT_43088_shake.rsp"},
+}
+
+func TestKATDigest(t *testing.T) {
+
+	for _, kat := range katDigests {
+		entropy := make([]byte, 48)
+		for i := range entropy {
+			entropy[i] = byte(i)
+		}
+		drbg := nist.NewDRBG(entropy, nil)
+
+		h := sha256.New()
+		fmt.Fprintf(h, "# %s\n\n", kat.header)
+		for i := 0; i < 100; i++ {
+			seed := make([]byte, 48)
+			drbg.Read(seed)
+			e := runKAT(kat.param, seed)
+			fmt.Fprintf(h, "count = %d\nseed = %X\npk = %X\nsk = %X\nct = %X\nss = %X\n\n", i, e.seed, e.pk, e.sk, e.ct, e.ss)
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != kat.digest {
+			t.Errorf("kat_test.go/TestKATDigest: %s response file digest is %s, expected %s", kat.param.Name(), got, kat.digest)
+		}
+	}
+}
+
+func TestKAT(t *testing.T) {
+
+	for _, kat := range katFiles {
+		path := filepath.Join("testdata", kat.file)
+		entries, err := readKAT(path)
+		if os.IsNotExist(err) {
+			t.Log("kat_test.go/TestKAT: no official", path, "for", kat.param.Name())
+			continue
+		}
+		if err != nil {
+			t.Fatal("kat_test.go/TestKAT:", err)
+		}
+		if len(entries) == 0 {
+			t.Fatal("kat_test.go/TestKAT: no entries in", path)
+		}
+		for _, e := range entries {
+			if got := runKAT(kat.param, e.seed); !got.equal(e) {
+				t.Error("kat_test.go/TestKAT:", kat.param.Name(), "mismatch at count", e.count)
+			}
+		}
+	}
+}
+
+// runKAT returns the entry computed from seed by PQCgenKAT_kem.c
+func runKAT(param *frodo.Parameters, seed []byte) *katEntry {
+
+	drbg := nist.NewDRBG(seed, nil)
+	e := &katEntry{seed: seed}
+
+	pk, sk, err := param.EncapsKeyGenFrom(drbg)
+	if err != nil {
+		return e
+	}
+	ct, ss, err := param.EncapsFrom(drbg, pk)
+	if err != nil {
+		return e
+	}
+	e.pk, _ = pk.MarshalBinary()
+	e.sk, _ = sk.MarshalBinary()
+	e.ct, _ = ct.MarshalBinary()
+	e.ss = ss
+
+	if !bytes.Equal(ss, param.Decaps(ct, sk)) {
+		e.ss = nil
+	}
+	return e
+}
+
+func (e *katEntry) equal(f *katEntry) bool {
+	return bytes.Equal(e.pk, f.pk) && bytes.Equal(e.sk, f.sk) && bytes.Equal(e.ct, f.ct) && bytes.Equal(e.ss, f.ss)
+}
+
+func readKAT(path string) ([]*katEntry, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*katEntry
+	var e *katEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, " = ", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s: malformed line %q", path, line)
+		}
+		if kv[0] == "count" {
+			e = new(katEntry)
+			if e.count, err = strconv.Atoi(kv[1]); err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+			continue
+		}
+		if e == nil {
+			return nil, fmt.Errorf("%s: %s before count", path, kv[0])
+		}
+		b, err := hex.DecodeString(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		switch kv[0] {
+		case "seed":
+			e.seed = b
+		case "pk":
+			e.pk = b
+		case "sk":
+			e.sk = b
+		case "ct":
+			e.ct = b
+		case "ss":
+			e.ss = b
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// testing a key pair and a ciphertext written by the baseline release 2caf64b,
+// frodo pkg Legacy must still decapsulate them
+// testdata/legacy holds seedA, b, seedS, pkh, c1, c2 and ss of its structures
+// and s, the entries of S as big-endian words
+
+func TestLegacyKAT(t *testing.T) {
+
+	for _, f := range []struct {
+		param *frodo.Parameters
+		file  string
+	}{
+		{frodo.Frodo640(), "Frodo640.txt"},
+		{frodo.Frodo976(), "Frodo976.txt"},
+	} {
+		v, err := readVectors(filepath.Join("testdata", "legacy", f.file))
+		if err != nil {
+			t.Fatal("kat_test.go/TestLegacyKAT:", err)
+		}
+		S := make([][]uint16, f.param.Dimension())
+		for i := range S {
+			S[i] = make([]uint16, f.param.NBar())
+			for j := range S[i] {
+				k := 2 * (i*f.param.NBar() + j)
+				S[i][j] = uint16(v["s"][k])<<8 | uint16(v["s"][k+1])
+			}
+		}
+		pk := &frodo.EncapsPublicKey{SeedA: v["seedA"], B: v["b"]}
+		sk := &frodo.EncapsSecretKey{SeedS: v["seedS"], SeedA: v["seedA"], B: v["b"], S: S, Pkh: v["pkh"]}
+		ct := &frodo.EncapsCipherText{C1: v["c1"], C2: v["c2"]}
+
+		legacy := f.param.Legacy()
+		if ss, err := legacy.DecapsChecked(ct, sk); err != nil || !bytes.Equal(ss, v["ss"]) {
+			t.Errorf("kat_test.go/TestLegacyKAT: %s does not decapsulate the baseline ciphertext: %v", legacy.Name(), err)
+		}
+		if ss, err := f.param.DecapsChecked(ct, sk); err != nil || bytes.Equal(ss, v["ss"]) {
+			t.Errorf("kat_test.go/TestLegacyKAT: %s must reject the baseline ciphertext implicitly: %v", f.param.Name(), err)
+		}
+		ct1, ss1 := legacy.Encaps(pk)
+		if !bytes.Equal(legacy.Decaps(ct1, sk), ss1) {
+			t.Errorf("kat_test.go/TestLegacyKAT: %s encapsulation to the baseline key does not decapsulate", legacy.Name())
+		}
+	}
+}
+
+// readVectors returns the hex values of the lines name = value of path
+func readVectors(path string) (map[string][]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v := make(map[string][]byte)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, " = ", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s: malformed line %q", path, line)
+		}
+		if v[kv[0]], err = hex.DecodeString(kv[1]); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, kv[0], err)
+		}
+	}
+	return v, nil
+}
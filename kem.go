@@ -1,16 +1,27 @@
 package frodo
 
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
 // KEM interface
 type KEM interface {
-	EncapsKeyGen() (pk *EncapsPublicKey, sk *EncapsSecretKey)     // returns key pair
-	Encaps(pk *EncapsPublicKey) (ct *EncapsCipherText, ss []byte) // using pk, returns ct and secret ss
-	Decaps(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte) // using sk, returns secret ss from ct
+	EncapsKeyGen() (pk *EncapsPublicKey, sk *EncapsSecretKey)                                      // returns key pair
+	EncapsKeyGenFrom(random io.Reader) (pk *EncapsPublicKey, sk *EncapsSecretKey, err error)       // returns key pair using random
+	Encaps(pk *EncapsPublicKey) (ct *EncapsCipherText, ss []byte)                                  // using pk, returns ct and secret ss
+	EncapsFrom(random io.Reader, pk *EncapsPublicKey) (ct *EncapsCipherText, ss []byte, err error) // using pk and random, returns ct and secret ss
+	Decaps(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte)                                  // using sk, returns secret ss from ct
+	DecapsChecked(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte, err error)                // using sk, returns secret ss from validated ct
 }
 
 // EncapsPublicKey structure
 type EncapsPublicKey struct {
 	SeedA []byte // $U({0,1}^lseedA)
 	B     []byte // packed matrix B
+
+	param *Parameters // parameter set of the key
 }
 
 // EncapsSecretKey structure
@@ -20,35 +31,57 @@ type EncapsSecretKey struct {
 	B     []byte     // packed matrix B
 	S     [][]uint16 // matrix є Zq (n*no)
 	Pkh   []byte     // {0,1}^lenpkh
+
+	param *Parameters // parameter set of the key
+	seed  []byte      // master seed of DeriveKeyPair, nil for keys from EncapsKeyGen
 }
 
 // EncapsCipherText structure
 type EncapsCipherText struct {
-	C1 []byte
-	C2 []byte
+	C1   []byte
+	C2   []byte
+	Salt []byte // $U({0,1}^lenSalt), empty for the 2019 and ephemeral parameter sets
+
+	param *Parameters // parameter set of the ciphertext
 }
 
-// EncapsKeyGen returns encapsulated key pair structure
+// EncapsKeyGen returns encapsulated key pair structure using crypto/rand,
+// it panics if the system randomness source fails
 func (param *Parameters) EncapsKeyGen() (pk *EncapsPublicKey, sk *EncapsSecretKey) {
 
-	pk, sk = new(EncapsPublicKey), new(EncapsSecretKey)
+	pk, sk, err := param.EncapsKeyGenFrom(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// EncapsKeyGenFrom returns encapsulated key pair structure,
+// s || seedSE || z are read from random
+func (param *Parameters) EncapsKeyGenFrom(random io.Reader) (pk *EncapsPublicKey, sk *EncapsSecretKey, err error) {
+
+	randomness, err := uniform(random, param.lens+param.lseedSE+param.lenz)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pk, sk = &EncapsPublicKey{param: param}, &EncapsSecretKey{param: param}
 
 	rLen := 2 * param.no * param.n * param.lenX
-	seedSE, z := uniform(param.lseedSE+1), uniform(param.lenz)
-	sk.SeedS = uniform(param.lens)
+	sk.SeedS = randomness[:param.lens]
+	seedSE := append([]byte{0x5f}, randomness[param.lens:param.lens+param.lseedSE]...)
+	z := randomness[param.lens+param.lseedSE:]
 
-	seedSE[0] = 0x5f
 	pk.SeedA = param.shake(z, param.lseedA)
 	r := param.shake(seedSE, rLen)
 
-	A := param.Gen(pk.SeedA)
-
 	rLen /= 2
-	sk.S = param.SampleMatrix(r[:rLen], param.no, param.n)
-	E := param.SampleMatrix(r[rLen:], param.no, param.n)
+	St := param.sampleSecret(r[:rLen])
+	E := param.sampleMatrix(r[rLen:], param.no, param.n)
+	sk.S = St.transpose().slices()
 
-	B := param.mulAddMatrices(A, sk.S, E)
-	pk.B = param.Pack(B)
+	B := param.mulAddAS(pk.SeedA, St, E) // B = A*S + E, A is generated row by row
+	pk.B = param.pack(B)
 
 	var pkh []byte
 	pkh = append(pkh, pk.SeedA...)
@@ -58,100 +91,143 @@ func (param *Parameters) EncapsKeyGen() (pk *EncapsPublicKey, sk *EncapsSecretKe
 	sk.SeedA = pk.SeedA
 	sk.B = pk.B
 
-	return
+	return pk, sk, nil
 }
 
-// Encaps returns encapsulated ciphertext and secret ss using public key
+// Encaps returns encapsulated ciphertext and secret ss using public key and crypto/rand,
+// it panics if the system randomness source fails
 func (param *Parameters) Encaps(pk *EncapsPublicKey) (ct *EncapsCipherText, ss []byte) {
 
-	ct = new(EncapsCipherText)
-
-	rLen := ((param.m*param.no)*2 + param.n*param.m) * param.lenX
-	m := uniform(param.lenM)
+	ct, ss, err := param.EncapsFrom(rand.Reader, pk)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
 
-	var pKey, seedSE []byte
-	pKey = append(pKey, pk.SeedA...)
-	pKey = append(pKey, pk.B...)
+// EncapsFrom returns encapsulated ciphertext and secret ss using public key,
+// message m || salt is read from random; pk is validated first
+func (param *Parameters) EncapsFrom(random io.Reader, pk *EncapsPublicKey) (ct *EncapsCipherText, ss []byte, err error) {
 
-	pkh := param.shake(pKey, param.lenpkh)
-	pkh = append(pkh, m...)
-	seed := param.shake(pkh, param.lseedSE+param.lenk)
+	if err := param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, nil, err
+	}
 
-	seedSE = append(seedSE, []byte{0x96}...)
-	seedSE = append(seedSE, seed[:(param.lseedSE)]...)
-	r := param.shake(seedSE, rLen)
+	randomness, err := uniform(random, param.EncapsulationSeedSize())
+	if err != nil {
+		return nil, nil, err
+	}
 
-	rLen = param.m * param.no * param.lenX
-	S1 := param.SampleMatrix(r[:rLen], param.m, param.no)
-	E1 := param.SampleMatrix(r[rLen:2*rLen], param.m, param.no)
-	E2 := param.SampleMatrix(r[2*rLen:], param.m, param.n)
+	ct, ss = param.encaps(pk, randomness)
+	return ct, ss, nil
+}
 
-	A := param.Gen(pk.SeedA)
-	B1 := param.mulAddMatrices(S1, A, E1)
+// EncapsBatchFrom returns count independent ciphertexts and secrets ss encapsulated to pk,
+// coins m || salt of every encapsulation are read from random; the products against A and B
+// of all the encapsulations are computed in one pass, see encapsBatch
+func (param *Parameters) EncapsBatchFrom(random io.Reader, pk *EncapsPublicKey, count int) (cts []*EncapsCipherText, sss [][]byte, err error) {
 
-	B := param.Unpack(pk.B, param.no, param.n)
-	V := param.mulAddMatrices(S1, B, E2)
-	C := param.sumMatrices(V, param.Encode(m))
+	if err := param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, nil, err
+	}
+	if count < 1 {
+		return nil, nil, fmt.Errorf("frodo: batch of %d encapsulations", count)
+	}
 
-	ct.C1 = param.Pack(B1)
-	ct.C2 = param.Pack(C)
+	size := param.EncapsulationSeedSize()
+	randomness, err := uniform(random, count*size)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	var temp, k []byte
-	k = append(k, seed[(param.lseedSE):]...)
-	temp = append(temp, ct.C1...)
-	temp = append(temp, ct.C2...)
-	temp = append(temp, k...)
+	coins := make([][]byte, count)
+	for i := range coins {
+		coins[i] = randomness[i*size : (i+1)*size]
+	}
+	s := param.NewScratch()
+	cts, sss = param.encapsBatch(s.expandPublicKey(pk), coins, s)
+	return cts, sss, nil
+}
 
-	ss = param.shake(temp, param.lenss)
+// encaps returns encapsulated ciphertext and secret ss using validated public key
+// and coins m || salt of EncapsulationSeedSize bytes
+func (param *Parameters) encaps(pk *EncapsPublicKey, coins []byte) (ct *EncapsCipherText, ss []byte) {
 
-	return
+	s := param.NewScratch()
+	return param.encapsKey(s.expandPublicKey(pk), coins, s)
 }
 
-// Decaps returns secret ss from ciphertext using secret key
-func (param *Parameters) Decaps(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte) {
+// encapsKey returns encapsulated ciphertext and secret ss using expanded public key
+// and coins m || salt of EncapsulationSeedSize bytes
+func (param *Parameters) encapsKey(key *expandedKey, coins []byte, s *Scratch) (ct *EncapsCipherText, ss []byte) {
 
-	B1, C := param.Unpack(ct.C1, param.m, param.no), param.Unpack(ct.C2, param.m, param.n)
-	B1S := param.mulMatrices(B1, sk.S)
+	b, ss := s.encaps(make([]byte, 0, param.CiphertextSize()), nil, key, coins)
+	return param.cipherText(b), ss
+}
 
-	M := param.subMatrices(C, B1S)
-	m1 := param.Decode(M)
+// cipherText returns the ciphertext of packed c1 || c2 || salt, it shares the memory of b
+func (param *Parameters) cipherText(b []byte) *EncapsCipherText {
 
-	var pkh, seedSE, k1 []byte
-	pkh = append(pkh, sk.Pkh...)
-	pkh = append(pkh, m1...)
+	c1, c2 := param.d*param.m*param.no/8, param.d*param.m*param.n/8
+	return &EncapsCipherText{C1: b[:c1:c1], C2: b[c1 : c1+c2 : c1+c2], Salt: b[c1+c2:], param: param}
+}
 
-	seed := param.shake(pkh, param.lseedSE+param.lenk)
+// encapsBatch returns a ciphertext and a secret ss for every coins m || salt using expanded
+// public key: S', E1 and E2 of all the encapsulations are stacked into tall matrices, so that
+// S'·A + E1 and S'·B + E2 are computed in a single pass over A and B
+func (param *Parameters) encapsBatch(key *expandedKey, coins [][]byte, s *Scratch) (cts []*EncapsCipherText, sss [][]byte) {
+
+	count := len(coins)
+	S1, E1, E2 := newMatrix(count*param.m, param.no), newMatrix(count*param.m, param.no), newMatrix(count*param.m, param.n)
+	ks := make([][]byte, count)
+	for i, coins := range coins {
+		lo, hi := i*param.m, (i+1)*param.m
+		s.derive(key.pkh, coins[:param.lenM], coins[param.lenM:], S1.slice(lo, hi), E1.slice(lo, hi), E2.slice(lo, hi))
+		ks[i] = append([]byte(nil), s.seed[param.lseedSE:]...)
+	}
 
-	seedSE = append(seedSE, []byte{0x96}...)
-	seedSE = append(seedSE, seed[:param.lseedSE]...)
+	B1 := param.mulAddSKeyTo(newMatrix(count*param.m, param.no), S1, key, E1, s) // B' = S'*A + E1
+	V := mulAddAB(S1, key.B, E2)                                                 // V = S'*B + E2
 
-	rLen := (2*param.no + param.n) * param.m * param.lenX
-	r := param.shake(seedSE, rLen)
+	cts, sss = make([]*EncapsCipherText, count), make([][]byte, count)
+	for i, coins := range coins {
+		lo, hi := i*param.m, (i+1)*param.m
+		param.encodeEntries(s.C.data, coins[:param.lenM])
+		addInto(s.C, s.C, V.slice(lo, hi)) // C = V + Encode(m)
 
-	rLen = param.m * param.no * param.lenX
-	S1 := param.SampleMatrix(r[:rLen], param.m, param.no)
-	E1 := param.SampleMatrix(r[rLen:2*rLen], param.m, param.no)
-	E2 := param.SampleMatrix(r[2*rLen:], param.m, param.n)
+		b := make([]byte, 0, param.CiphertextSize())
+		b = param.packEntries(b, B1.slice(lo, hi).data)
+		b = param.packEntries(b, s.C.data)
+		b = append(b, coins[param.lenM:]...)
 
-	A := param.Gen(sk.SeedA)
-	B := param.Unpack(sk.B, param.no, param.n)
-
-	B2 := param.mulAddMatrices(S1, A, E1)
-	V := param.mulAddMatrices(S1, B, E2)
-	C1 := param.sumMatrices(V, param.Encode(m1))
+		cts[i] = param.cipherText(b)
+		sss[i] = s.shake(nil, param.lenss, b, ks[i]) // ss = SHAKE(c1 || c2 || salt || k)
+	}
+	return cts, sss
+}
 
-	var res []byte
-	res = append(res, ct.C1...)
-	res = append(res, ct.C2...)
+// Decaps returns secret ss from ciphertext using secret key,
+// it panics on malformed ct or sk, use DecapsChecked for untrusted input
+func (param *Parameters) Decaps(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte) {
 
-	if eqMatrices(B1, B2) == true && eqMatrices(C, C1) == true {
-		k1 = append(k1, seed[(param.lseedSE):]...)
-		res = append(res, k1...)
-	} else {
-		res = append(res, sk.SeedS...)
+	ss, err := param.DecapsChecked(ct, sk)
+	if err != nil {
+		panic(err)
 	}
+	return
+}
 
-	ss = param.shake(res, param.lenss)
+// DecapsChecked returns secret ss from ciphertext using secret key,
+// ct and sk are validated before any arithmetic
+func (param *Parameters) DecapsChecked(ct *EncapsCipherText, sk *EncapsSecretKey) (ss []byte, err error) {
 
-	return
+	if err := param.ValidateEncapsSecretKey(sk); err != nil {
+		return nil, err
+	}
+	if err := param.ValidateEncapsCipherText(ct); err != nil {
+		return nil, err
+	}
+
+	s := param.NewScratch()
+	return s.decaps(nil, s.expandSecretKey(sk), ct.C1, ct.C2, ct.Salt), nil
 }
@@ -0,0 +1,18 @@
+package frodo
+
+//go:generate go run gen_kernels.go
+
+// kernels are the hot loops of a parameter set with its dimensions, modulus and error table
+// known at compile time, so that bounds checks are eliminated and loops are unrolled.
+// kernels_gen.go generates them for the sets of [FKEM]; parameter sets without kernels
+// use the generic code, which also remains the reference of the generated one.
+// rowAS and rowSA are nil on amd64, where the assembly dot and axpy are faster
+// than the fused Go loops
+type kernels struct {
+	entries func(row []uint16, b []byte)           // a row of A from 2n bytes of Gen output
+	pack    func(b []byte, c []uint16)             // packEntries, len(c) is a multiple of 8
+	unpack  func(c []uint16, b []byte)             // unpackEntries, len(c) is a multiple of 8
+	sample  func(e []uint16, r []byte)             // sampleEntries
+	rowAS   func(c, a, st []uint16)                // a row of A·S, St has 8 rows
+	rowSA   func(p, s []uint16, i int, a []uint16) // row i of A accumulated into 8 rows of S·A
+}
@@ -0,0 +1,183 @@
+// Code generated by gen_kernels.go; DO NOT EDIT.
+
+package frodo
+
+// pack15 packs the low 15 bits of the entries c into b, 8 entries into 15 bytes, len(c) is a multiple of 8
+func pack15(b []byte, c []uint16) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[15]byte)(b)
+		d[0] = byte((e[0] & 0x7fff) >> 7)
+		d[1] = byte(e[0]<<1) | byte((e[1]&0x7fff)>>14)
+		d[2] = byte((e[1] & 0x7fff) >> 6)
+		d[3] = byte(e[1]<<2) | byte((e[2]&0x7fff)>>13)
+		d[4] = byte((e[2] & 0x7fff) >> 5)
+		d[5] = byte(e[2]<<3) | byte((e[3]&0x7fff)>>12)
+		d[6] = byte((e[3] & 0x7fff) >> 4)
+		d[7] = byte(e[3]<<4) | byte((e[4]&0x7fff)>>11)
+		d[8] = byte((e[4] & 0x7fff) >> 3)
+		d[9] = byte(e[4]<<5) | byte((e[5]&0x7fff)>>10)
+		d[10] = byte((e[5] & 0x7fff) >> 2)
+		d[11] = byte(e[5]<<6) | byte((e[6]&0x7fff)>>9)
+		d[12] = byte((e[6] & 0x7fff) >> 1)
+		d[13] = byte(e[6]<<7) | byte((e[7]&0x7fff)>>8)
+		d[14] = byte(e[7])
+		b, c = b[15:], c[8:]
+	}
+}
+
+// unpack15 unpacks the 15·len(c) bits of b into the entries c, 15 bytes into 8 entries, len(c) is a multiple of 8
+func unpack15(c []uint16, b []byte) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[15]byte)(b)
+		e[0] = (uint16(d[0])<<7 | uint16(d[1])>>1) & 0x7fff
+		e[1] = (uint16(d[1])<<14 | uint16(d[2])<<6 | uint16(d[3])>>2) & 0x7fff
+		e[2] = (uint16(d[3])<<13 | uint16(d[4])<<5 | uint16(d[5])>>3) & 0x7fff
+		e[3] = (uint16(d[5])<<12 | uint16(d[6])<<4 | uint16(d[7])>>4) & 0x7fff
+		e[4] = (uint16(d[7])<<11 | uint16(d[8])<<3 | uint16(d[9])>>5) & 0x7fff
+		e[5] = (uint16(d[9])<<10 | uint16(d[10])<<2 | uint16(d[11])>>6) & 0x7fff
+		e[6] = (uint16(d[11])<<9 | uint16(d[12])<<1 | uint16(d[13])>>7) & 0x7fff
+		e[7] = (uint16(d[13])<<8 | uint16(d[14])) & 0x7fff
+		b, c = b[15:], c[8:]
+	}
+}
+
+// pack16 packs the low 16 bits of the entries c into b, 8 entries into 16 bytes, len(c) is a multiple of 8
+func pack16(b []byte, c []uint16) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[16]byte)(b)
+		d[0] = byte(e[0] >> 8)
+		d[1] = byte(e[0])
+		d[2] = byte(e[1] >> 8)
+		d[3] = byte(e[1])
+		d[4] = byte(e[2] >> 8)
+		d[5] = byte(e[2])
+		d[6] = byte(e[3] >> 8)
+		d[7] = byte(e[3])
+		d[8] = byte(e[4] >> 8)
+		d[9] = byte(e[4])
+		d[10] = byte(e[5] >> 8)
+		d[11] = byte(e[5])
+		d[12] = byte(e[6] >> 8)
+		d[13] = byte(e[6])
+		d[14] = byte(e[7] >> 8)
+		d[15] = byte(e[7])
+		b, c = b[16:], c[8:]
+	}
+}
+
+// unpack16 unpacks the 16·len(c) bits of b into the entries c, 16 bytes into 8 entries, len(c) is a multiple of 8
+func unpack16(c []uint16, b []byte) {
+
+	for len(c) >= 8 {
+		e, d := (*[8]uint16)(c), (*[16]byte)(b)
+		e[0] = uint16(d[0])<<8 | uint16(d[1])
+		e[1] = uint16(d[2])<<8 | uint16(d[3])
+		e[2] = uint16(d[4])<<8 | uint16(d[5])
+		e[3] = uint16(d[6])<<8 | uint16(d[7])
+		e[4] = uint16(d[8])<<8 | uint16(d[9])
+		e[5] = uint16(d[10])<<8 | uint16(d[11])
+		e[6] = uint16(d[12])<<8 | uint16(d[13])
+		e[7] = uint16(d[14])<<8 | uint16(d[15])
+		b, c = b[16:], c[8:]
+	}
+}
+
+// kernels640 are the kernels of the sets with n = 640
+var kernels640 = kernels{entries: entries640, pack: pack15, unpack: unpack15, sample: sample640}
+
+// entries640 sets the 640 entries of row to the 16-bit little-endian words of b reduced modulo q
+func entries640(row []uint16, b []byte) {
+
+	r, p := (*[640]uint16)(row), (*[1280]byte)(b)
+	for j := range r {
+		r[j] = (uint16(p[2*j]) | uint16(p[2*j+1])<<8) & 0x7fff
+	}
+}
+
+// sample640 samples the entries e from the 16-bit little-endian words of r, see Sample
+func sample640(e []uint16, r []byte) {
+
+	r = r[:2*len(e)]
+	for i := range e {
+		w := uint16(r[2*i]) | uint16(r[2*i+1])<<8
+		t, sign := w>>1, w&1
+		sum := (4643-t)>>15 +
+			(13363-t)>>15 +
+			(20579-t)>>15 +
+			(25843-t)>>15 +
+			(29227-t)>>15 +
+			(31145-t)>>15 +
+			(32103-t)>>15 +
+			(32525-t)>>15 +
+			(32689-t)>>15 +
+			(32745-t)>>15 +
+			(32762-t)>>15 +
+			(32766-t)>>15
+		e[i] = ((-sign ^ sum) + sign) & 0x7fff
+	}
+}
+
+// kernels976 are the kernels of the sets with n = 976
+var kernels976 = kernels{entries: entries976, pack: pack16, unpack: unpack16, sample: sample976}
+
+// entries976 sets the 976 entries of row to the 16-bit little-endian words of b reduced modulo q
+func entries976(row []uint16, b []byte) {
+
+	r, p := (*[976]uint16)(row), (*[1952]byte)(b)
+	for j := range r {
+		r[j] = (uint16(p[2*j]) | uint16(p[2*j+1])<<8) & 0xffff
+	}
+}
+
+// sample976 samples the entries e from the 16-bit little-endian words of r, see Sample
+func sample976(e []uint16, r []byte) {
+
+	r = r[:2*len(e)]
+	for i := range e {
+		w := uint16(r[2*i]) | uint16(r[2*i+1])<<8
+		t, sign := w>>1, w&1
+		sum := (5638-t)>>15 +
+			(15915-t)>>15 +
+			(23689-t)>>15 +
+			(28571-t)>>15 +
+			(31116-t)>>15 +
+			(32217-t)>>15 +
+			(32613-t)>>15 +
+			(32731-t)>>15 +
+			(32760-t)>>15 +
+			(32766-t)>>15
+		e[i] = ((-sign ^ sum) + sign) & 0xffff
+	}
+}
+
+// kernels1344 are the kernels of the sets with n = 1344
+var kernels1344 = kernels{entries: entries1344, pack: pack16, unpack: unpack16, sample: sample1344}
+
+// entries1344 sets the 1344 entries of row to the 16-bit little-endian words of b reduced modulo q
+func entries1344(row []uint16, b []byte) {
+
+	r, p := (*[1344]uint16)(row), (*[2688]byte)(b)
+	for j := range r {
+		r[j] = (uint16(p[2*j]) | uint16(p[2*j+1])<<8) & 0xffff
+	}
+}
+
+// sample1344 samples the entries e from the 16-bit little-endian words of r, see Sample
+func sample1344(e []uint16, r []byte) {
+
+	r = r[:2*len(e)]
+	for i := range e {
+		w := uint16(r[2*i]) | uint16(r[2*i+1])<<8
+		t, sign := w>>1, w&1
+		sum := (9142-t)>>15 +
+			(23462-t)>>15 +
+			(30338-t)>>15 +
+			(32361-t)>>15 +
+			(32725-t)>>15 +
+			(32765-t)>>15
+		e[i] = ((-sign ^ sum) + sign) & 0xffff
+	}
+}
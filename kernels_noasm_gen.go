@@ -0,0 +1,129 @@
+// Code generated by gen_kernels.go; DO NOT EDIT.
+
+//go:build !amd64 || purego
+
+package frodo
+
+// the products are specialized only without the assembly dot and axpy, which are faster
+func init() {
+	kernels640.rowAS, kernels640.rowSA = rowAS640, rowSA640
+	kernels976.rowAS, kernels976.rowSA = rowAS976, rowSA976
+	kernels1344.rowAS, kernels1344.rowSA = rowAS1344, rowSA1344
+}
+
+// rowAS640 sets the 8 entries c of a row of A·S to the dot products of the row a of A
+// with the rows of Sᵀ, st holds the 8 rows of 640 entries
+func rowAS640(c, a, st []uint16) {
+
+	c, a, st = c[:8], a[:640], st[:5120]
+	s0, s1, s2, s3, s4, s5, s6, s7 := st[0:640], st[640:1280], st[1280:1920], st[1920:2560], st[2560:3200], st[3200:3840], st[3840:4480], st[4480:5120]
+	var c0, c1, c2, c3, c4, c5, c6, c7 uint16
+	for j, x := range a {
+		c0 += s0[j] * x
+		c1 += s1[j] * x
+		c2 += s2[j] * x
+		c3 += s3[j] * x
+		c4 += s4[j] * x
+		c5 += s5[j] * x
+		c6 += s6[j] * x
+		c7 += s7[j] * x
+	}
+	c[0], c[1], c[2], c[3], c[4], c[5], c[6], c[7] = c0, c1, c2, c3, c4, c5, c6, c7
+}
+
+// rowSA640 adds s[k·640+i]·a to the row k of p for the 8 rows of p and s of 640 entries,
+// a is the row i of A
+func rowSA640(p, s []uint16, i int, a []uint16) {
+
+	p, s, a = p[:5120], s[:5120], a[:640]
+	p0, p1, p2, p3, p4, p5, p6, p7 := p[0:640], p[640:1280], p[1280:1920], p[1920:2560], p[2560:3200], p[3200:3840], p[3840:4480], p[4480:5120]
+	s0, s1, s2, s3, s4, s5, s6, s7 := s[0+i], s[640+i], s[1280+i], s[1920+i], s[2560+i], s[3200+i], s[3840+i], s[4480+i]
+	for j, x := range a {
+		p0[j] += s0 * x
+		p1[j] += s1 * x
+		p2[j] += s2 * x
+		p3[j] += s3 * x
+		p4[j] += s4 * x
+		p5[j] += s5 * x
+		p6[j] += s6 * x
+		p7[j] += s7 * x
+	}
+}
+
+// rowAS976 sets the 8 entries c of a row of A·S to the dot products of the row a of A
+// with the rows of Sᵀ, st holds the 8 rows of 976 entries
+func rowAS976(c, a, st []uint16) {
+
+	c, a, st = c[:8], a[:976], st[:7808]
+	s0, s1, s2, s3, s4, s5, s6, s7 := st[0:976], st[976:1952], st[1952:2928], st[2928:3904], st[3904:4880], st[4880:5856], st[5856:6832], st[6832:7808]
+	var c0, c1, c2, c3, c4, c5, c6, c7 uint16
+	for j, x := range a {
+		c0 += s0[j] * x
+		c1 += s1[j] * x
+		c2 += s2[j] * x
+		c3 += s3[j] * x
+		c4 += s4[j] * x
+		c5 += s5[j] * x
+		c6 += s6[j] * x
+		c7 += s7[j] * x
+	}
+	c[0], c[1], c[2], c[3], c[4], c[5], c[6], c[7] = c0, c1, c2, c3, c4, c5, c6, c7
+}
+
+// rowSA976 adds s[k·976+i]·a to the row k of p for the 8 rows of p and s of 976 entries,
+// a is the row i of A
+func rowSA976(p, s []uint16, i int, a []uint16) {
+
+	p, s, a = p[:7808], s[:7808], a[:976]
+	p0, p1, p2, p3, p4, p5, p6, p7 := p[0:976], p[976:1952], p[1952:2928], p[2928:3904], p[3904:4880], p[4880:5856], p[5856:6832], p[6832:7808]
+	s0, s1, s2, s3, s4, s5, s6, s7 := s[0+i], s[976+i], s[1952+i], s[2928+i], s[3904+i], s[4880+i], s[5856+i], s[6832+i]
+	for j, x := range a {
+		p0[j] += s0 * x
+		p1[j] += s1 * x
+		p2[j] += s2 * x
+		p3[j] += s3 * x
+		p4[j] += s4 * x
+		p5[j] += s5 * x
+		p6[j] += s6 * x
+		p7[j] += s7 * x
+	}
+}
+
+// rowAS1344 sets the 8 entries c of a row of A·S to the dot products of the row a of A
+// with the rows of Sᵀ, st holds the 8 rows of 1344 entries
+func rowAS1344(c, a, st []uint16) {
+
+	c, a, st = c[:8], a[:1344], st[:10752]
+	s0, s1, s2, s3, s4, s5, s6, s7 := st[0:1344], st[1344:2688], st[2688:4032], st[4032:5376], st[5376:6720], st[6720:8064], st[8064:9408], st[9408:10752]
+	var c0, c1, c2, c3, c4, c5, c6, c7 uint16
+	for j, x := range a {
+		c0 += s0[j] * x
+		c1 += s1[j] * x
+		c2 += s2[j] * x
+		c3 += s3[j] * x
+		c4 += s4[j] * x
+		c5 += s5[j] * x
+		c6 += s6[j] * x
+		c7 += s7[j] * x
+	}
+	c[0], c[1], c[2], c[3], c[4], c[5], c[6], c[7] = c0, c1, c2, c3, c4, c5, c6, c7
+}
+
+// rowSA1344 adds s[k·1344+i]·a to the row k of p for the 8 rows of p and s of 1344 entries,
+// a is the row i of A
+func rowSA1344(p, s []uint16, i int, a []uint16) {
+
+	p, s, a = p[:10752], s[:10752], a[:1344]
+	p0, p1, p2, p3, p4, p5, p6, p7 := p[0:1344], p[1344:2688], p[2688:4032], p[4032:5376], p[5376:6720], p[6720:8064], p[8064:9408], p[9408:10752]
+	s0, s1, s2, s3, s4, s5, s6, s7 := s[0+i], s[1344+i], s[2688+i], s[4032+i], s[5376+i], s[6720+i], s[8064+i], s[9408+i]
+	for j, x := range a {
+		p0[j] += s0 * x
+		p1[j] += s1 * x
+		p2[j] += s2 * x
+		p3[j] += s3 * x
+		p4[j] += s4 * x
+		p5[j] += s5 * x
+		p6[j] += s6 * x
+		p7[j] += s7 * x
+	}
+}
@@ -0,0 +1,356 @@
+package frodo
+
+import (
+	"crypto/subtle"
+	"sync"
+)
+
+// matrix is a rows-by-cols matrix stored row by row in one contiguous slice,
+// row i is data[i*stride : i*stride+cols]. Entries live in Z_(2^16): uint16 arithmetic
+// wraps around naturally and, since q+1 divides 2^16, reduce brings them into Zq
+// once at the end instead of masking every step
+type matrix struct {
+	rows, cols, stride int
+	data               []uint16
+}
+
+// newMatrix returns the zero rows-by-cols matrix
+func newMatrix(rows, cols int) *matrix {
+	return &matrix{rows: rows, cols: cols, stride: cols, data: make([]uint16, rows*cols)}
+}
+
+// matrixOf returns a copy of the n1-by-n2 matrix A
+func matrixOf(A [][]uint16) *matrix {
+
+	M := newMatrix(len(A), len(A[0]))
+	for i := range A {
+		copy(M.row(i), A[i])
+	}
+	return M
+}
+
+// slices returns a copy of M as separately allocated rows
+func (M *matrix) slices() [][]uint16 {
+
+	A := make([][]uint16, M.rows)
+	for i := range A {
+		A[i] = append([]uint16(nil), M.row(i)...)
+	}
+	return A
+}
+
+// row returns the row i of M, it shares the memory of M
+func (M *matrix) row(i int) []uint16 {
+	return M.data[i*M.stride : i*M.stride+M.cols]
+}
+
+// slice returns the rows lo, ..., hi-1 of M, it shares the memory of M
+func (M *matrix) slice(lo, hi int) *matrix {
+	return &matrix{rows: hi - lo, cols: M.cols, stride: M.stride, data: M.data[lo*M.stride : (hi-1)*M.stride+M.cols]}
+}
+
+// transpose returns Mᵀ
+func (M *matrix) transpose() *matrix {
+
+	T := newMatrix(M.cols, M.rows)
+	for i := 0; i < M.rows; i++ {
+		for j, e := range M.row(i) {
+			T.data[j*T.stride+i] = e
+		}
+	}
+	return T
+}
+
+// reduce reduces the entries of M modulo q in place and returns M
+func (param *Parameters) reduce(M *matrix) *matrix {
+
+	for i := 0; i < M.rows; i++ {
+		row := M.row(i)
+		for j := range row {
+			row[j] &= param.q
+		}
+	}
+	return M
+}
+
+// dotGeneric returns Σ a[j]·b[j] (mod 2^16), it is the portable dot of matrix_noasm.go
+// and the reference of the assembly kernels
+func dotGeneric(a, b []uint16) uint16 {
+
+	var sum uint16
+	b = b[:len(a)]
+	for j, e := range a {
+		sum += e * b[j]
+	}
+	return sum
+}
+
+// axpyGeneric adds c·x to y (mod 2^16), it is the portable axpy of matrix_noasm.go
+// and the reference of the assembly kernels
+func axpyGeneric(y []uint16, c uint16, x []uint16) {
+
+	x = x[:len(y)]
+	for j := range y {
+		y[j] += c * x[j]
+	}
+}
+
+// mulAddABt returns A·Bᵀ + E (mod 2^16) for Bᵀ given by its rows, E may be nil:
+// every entry is the dot product of two contiguous rows, like A·S with S stored as Sᵀ
+func mulAddABt(A, Bt, E *matrix) *matrix {
+	return mulAddABtTo(newMatrix(A.rows, Bt.rows), A, Bt, E)
+}
+
+// mulAddABtTo sets C to A·Bᵀ + E (mod 2^16) and returns C, C must not overlap A and Bt
+func mulAddABtTo(C, A, Bt, E *matrix) *matrix {
+
+	for i := 0; i < A.rows; i++ {
+		a, c := A.row(i), C.row(i)
+		for k := range c {
+			c[k] = dot(a, Bt.row(k))
+		}
+		if E != nil {
+			addTo(c, E.row(i))
+		}
+	}
+	return C
+}
+
+// mulAddAB returns A·B + E (mod 2^16), E may be nil: the rows of B are
+// traversed once and accumulated into the rows of the result, like S'·A
+func mulAddAB(A, B, E *matrix) *matrix {
+	return mulAddABTo(newMatrix(A.rows, B.cols), A, B, E)
+}
+
+// mulAddABTo sets C to A·B + E (mod 2^16) and returns C, C must not overlap A and B
+func mulAddABTo(C, A, B, E *matrix) *matrix {
+
+	C.setTo(E)
+	for i := 0; i < B.rows; i++ {
+		b := B.row(i)
+		for k := 0; k < A.rows; k++ {
+			axpy(C.row(k), A.data[k*A.stride+i], b)
+		}
+	}
+	return C
+}
+
+// setTo copies E to M, or zeroes M if E is nil
+func (M *matrix) setTo(E *matrix) {
+
+	for k := 0; k < M.rows; k++ {
+		row := M.row(k)
+		if E != nil {
+			copy(row, E.row(k))
+			continue
+		}
+		for j := range row {
+			row[j] = 0
+		}
+	}
+}
+
+// addTo adds x to y (mod 2^16)
+func addTo(y, x []uint16) {
+
+	x = x[:len(y)]
+	for j := range y {
+		y[j] += x[j]
+	}
+}
+
+// add returns A + B (mod 2^16)
+func add(A, B *matrix) *matrix {
+	return addInto(newMatrix(A.rows, A.cols), A, B)
+}
+
+// addInto sets C to A + B (mod 2^16) and returns C, C may be A but not B
+func addInto(C, A, B *matrix) *matrix {
+
+	for i := 0; i < A.rows; i++ {
+		c := C.row(i)
+		copy(c, A.row(i))
+		addTo(c, B.row(i))
+	}
+	return C
+}
+
+// sub returns A - B (mod 2^16)
+func sub(A, B *matrix) *matrix {
+	return subInto(newMatrix(A.rows, A.cols), A, B)
+}
+
+// subInto sets C to A - B (mod 2^16) and returns C, C may be A or B
+func subInto(C, A, B *matrix) *matrix {
+
+	for i := 0; i < A.rows; i++ {
+		a, b, c := A.row(i), B.row(i), C.row(i)
+		for j := range c {
+			c[j] = a[j] - b[j]
+		}
+	}
+	return C
+}
+
+// ctEqual returns 1 if A == B and 0 otherwise,
+// in constant time: all entries are compared
+func ctEqual(A, B *matrix) int {
+
+	var d uint16
+	for i := 0; i < A.rows; i++ {
+		a, b := A.row(i), B.row(i)
+		for j := range a {
+			d |= a[j] ^ b[j]
+		}
+	}
+	return subtle.ConstantTimeEq(int32(d), 0)
+}
+
+// rowsPerChunk is the number of rows of A generated at once by the streaming products,
+// like the reference implementation, A is never materialized: 8 rows are 21 KB for n = 1344
+const rowsPerChunk = 8
+
+// forRows calls f on consecutive ranges [lo, hi) of the rows of A, each a multiple of rowsPerChunk,
+// with the index of the range; the ranges run on Concurrency goroutines and f returns when all are done
+func (param *Parameters) forRows(f func(w, lo, hi int)) {
+
+	workers, chunks := param.Concurrency(), param.no/rowsPerChunk
+	if workers > chunks {
+		workers = chunks
+	}
+	if workers == 1 {
+		f(0, 0, param.no)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := chunks*w/workers*rowsPerChunk, chunks*(w+1)/workers*rowsPerChunk
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			f(w, lo, hi)
+		}(w, lo, hi)
+	}
+	wg.Wait()
+}
+
+// genMatrix returns the no-by-no matrix A of seed
+func (param *Parameters) genMatrix(seed []byte) *matrix {
+
+	A := newMatrix(param.no, param.no)
+	param.forRows(func(_, lo, hi int) {
+		param.newRows(seed).rows(A.data[lo*A.stride:hi*A.stride], lo)
+	})
+	return A
+}
+
+// rowsOf returns a generator of the rows of A for seedA and a buffer of rowsPerChunk rows
+// for the goroutine w, the first goroutine reuses the ones of scratch s if it is not nil
+func (param *Parameters) rowsOf(s *Scratch, w int, seedA []byte) (rowsGenerator, []uint16) {
+
+	if s != nil && w == 0 {
+		return s.rows(seedA), s.chunk
+	}
+	return param.newRows(seedA), make([]uint16, rowsPerChunk*param.no)
+}
+
+// mulAddAS returns A·S + E (mod 2^16) for A generated from seedA and S given as Sᵀ
+func (param *Parameters) mulAddAS(seedA []byte, St, E *matrix) *matrix {
+	return param.mulAddASTo(newMatrix(param.no, St.rows), seedA, St, E, nil)
+}
+
+// mulAddASTo sets C to A·S + E (mod 2^16) and returns C, see mulAddAS:
+// the rows of the result are independent and shared among the goroutines
+func (param *Parameters) mulAddASTo(C *matrix, seedA []byte, St, E *matrix, s *Scratch) *matrix {
+
+	if param.Concurrency() == 1 { // without the closure of forRows, it would be allocated
+		gen, chunk := param.rowsOf(s, 0, seedA)
+		param.accAS(C, St, E, gen, chunk, 0, param.no)
+		return C
+	}
+	param.forRows(func(w, lo, hi int) {
+		gen, chunk := param.rowsOf(s, w, seedA)
+		param.accAS(C, St, E, gen, chunk, lo, hi)
+	})
+	return C
+}
+
+// accAS sets the rows lo, ..., hi-1 of C to those of A·S + E: A is expanded
+// rowsPerChunk rows at a time into chunk and every row is consumed by dot products
+func (param *Parameters) accAS(C, St, E *matrix, gen rowsGenerator, chunk []uint16, lo, hi int) {
+
+	kern := param.kern != nil && param.kern.rowAS != nil && St.rows == param.n && St.stride == param.no
+	for i := lo; i < hi; i += rowsPerChunk {
+		gen.rows(chunk, i)
+		for r := 0; r < rowsPerChunk; r++ {
+			a, c := chunk[r*param.no:(r+1)*param.no], C.row(i+r)
+			if kern {
+				param.kern.rowAS(c, a, St.data)
+			} else {
+				for k := range c {
+					c[k] = dot(a, St.row(k))
+				}
+			}
+			addTo(c, E.row(i+r))
+		}
+	}
+}
+
+// mulAddSA returns S·A + E (mod 2^16) for A generated from seedA
+func (param *Parameters) mulAddSA(S *matrix, seedA []byte, E *matrix) *matrix {
+	return param.mulAddSATo(newMatrix(S.rows, param.no), S, seedA, E, nil)
+}
+
+// mulAddSATo sets C to S·A + E (mod 2^16) and returns C, see mulAddSA.
+// Every goroutine but the first accumulates its rows of A into its own partial product,
+// the sum of the partial products mod 2^16 does not depend on their order,
+// so the result is the sequential one
+func (param *Parameters) mulAddSATo(C, S *matrix, seedA []byte, E *matrix, s *Scratch) *matrix {
+
+	C.setTo(E)
+	if param.Concurrency() == 1 {
+		gen, chunk := param.rowsOf(s, 0, seedA)
+		param.accSA(C, S, gen, chunk, 0, param.no)
+		return C
+	}
+
+	partial := make([]*matrix, param.Concurrency())
+	param.forRows(func(w, lo, hi int) {
+		P := C
+		if w > 0 {
+			P = newMatrix(S.rows, param.no)
+			partial[w] = P
+		}
+		gen, chunk := param.rowsOf(s, w, seedA)
+		param.accSA(P, S, gen, chunk, lo, hi)
+	})
+	for _, P := range partial {
+		if P != nil {
+			addInto(C, C, P)
+		}
+	}
+	return C
+}
+
+// accSA adds S·A restricted to the rows lo, ..., hi-1 of A to P: A is expanded
+// rowsPerChunk rows at a time into chunk and row i is accumulated into every row of P
+func (param *Parameters) accSA(P, S *matrix, gen rowsGenerator, chunk []uint16, lo, hi int) {
+
+	kern := param.kern != nil && param.kern.rowSA != nil && S.rows%param.m == 0 && S.stride == param.no && P.stride == param.no
+	for i := lo; i < hi; i += rowsPerChunk {
+		gen.rows(chunk, i)
+		for r := 0; r < rowsPerChunk; r++ {
+			a := chunk[r*param.no : (r+1)*param.no]
+			if kern { // S is a stack of m-by-n matrices in a batch
+				for k := 0; k < S.rows; k += param.m {
+					param.kern.rowSA(P.data[k*param.no:], S.data[k*param.no:], i+r, a)
+				}
+				continue
+			}
+			for k := 0; k < S.rows; k++ {
+				axpy(P.row(k), S.data[k*S.stride+i+r], a)
+			}
+		}
+	}
+}
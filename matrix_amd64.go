@@ -0,0 +1,44 @@
+//go:build amd64 && !purego
+
+package frodo
+
+import "golang.org/x/sys/cpu"
+
+// useAVX2 selects the 256-bit kernels of matrix_amd64.s, SSE2 is the amd64 baseline
+var useAVX2 = cpu.X86.HasAVX2
+
+// dot returns Σ a[j]·b[j] (mod 2^16), len(b) ≥ len(a)
+func dot(a, b []uint16) uint16 {
+
+	b = b[:len(a)]
+	if useAVX2 {
+		return dotAVX2(a, b)
+	}
+	return dotSSE2(a, b)
+}
+
+// axpy adds c·x to y (mod 2^16), len(x) ≥ len(y)
+func axpy(y []uint16, c uint16, x []uint16) {
+
+	x = x[:len(y)]
+	if useAVX2 {
+		axpyAVX2(y, c, x)
+		return
+	}
+	axpySSE2(y, c, x)
+}
+
+// the kernels multiply and add 16-bit lanes with VPMULLW/PMULLW and VPADDW/PADDW,
+// which wrap around modulo 2^16 exactly like the uint16 arithmetic of the Go code
+
+//go:noescape
+func dotAVX2(a, b []uint16) uint16
+
+//go:noescape
+func dotSSE2(a, b []uint16) uint16
+
+//go:noescape
+func axpyAVX2(y []uint16, c uint16, x []uint16)
+
+//go:noescape
+func axpySSE2(y []uint16, c uint16, x []uint16)
@@ -0,0 +1,167 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func dotAVX2(a, b []uint16) uint16
+TEXT ·dotAVX2(SB), NOSPLIT, $0-50
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	VPXOR Y0, Y0, Y0
+
+loop16:
+	CMPQ CX, $16
+	JB   reduce
+	VMOVDQU (SI), Y1
+	VPMULLW (DI), Y1, Y1
+	VPADDW  Y1, Y0, Y0
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $16, CX
+	JMP     loop16
+
+reduce:
+	VEXTRACTI128 $1, Y0, X1
+	VPADDW       X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPADDW       X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPADDW       X1, X0, X0
+	VPSHUFLW     $0xb1, X0, X1
+	VPADDW       X1, X0, X0
+	VMOVQ        X0, AX
+	VZEROUPPER
+
+tail:
+	TESTQ   CX, CX
+	JZ      done
+	MOVWLZX (SI), R8
+	MOVWLZX (DI), R9
+	IMULL   R9, R8
+	ADDL    R8, AX
+	ADDQ    $2, SI
+	ADDQ    $2, DI
+	DECQ    CX
+	JMP     tail
+
+done:
+	MOVW AX, ret+48(FP)
+	RET
+
+// func dotSSE2(a, b []uint16) uint16
+TEXT ·dotSSE2(SB), NOSPLIT, $0-50
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	PXOR X0, X0
+
+loop8:
+	CMPQ   CX, $8
+	JB     reduce
+	MOVOU  (SI), X1
+	MOVOU  (DI), X2
+	PMULLW X2, X1
+	PADDW  X1, X0
+	ADDQ   $16, SI
+	ADDQ   $16, DI
+	SUBQ   $8, CX
+	JMP    loop8
+
+reduce:
+	PSHUFD  $0x4e, X0, X1
+	PADDW   X1, X0
+	PSHUFD  $0xb1, X0, X1
+	PADDW   X1, X0
+	PSHUFLW $0xb1, X0, X1
+	PADDW   X1, X0
+	MOVQ    X0, AX
+
+tail:
+	TESTQ   CX, CX
+	JZ      done
+	MOVWLZX (SI), R8
+	MOVWLZX (DI), R9
+	IMULL   R9, R8
+	ADDL    R8, AX
+	ADDQ    $2, SI
+	ADDQ    $2, DI
+	DECQ    CX
+	JMP     tail
+
+done:
+	MOVW AX, ret+48(FP)
+	RET
+
+// func axpyAVX2(y []uint16, c uint16, x []uint16)
+TEXT ·axpyAVX2(SB), NOSPLIT, $0-56
+	MOVQ    y_base+0(FP), SI
+	MOVQ    y_len+8(FP), CX
+	MOVWLZX c+24(FP), AX
+	MOVQ    x_base+32(FP), DI
+	MOVQ    AX, X2
+	VPBROADCASTW X2, Y2
+
+loop16:
+	CMPQ    CX, $16
+	JB      tail
+	VPMULLW (DI), Y2, Y0
+	VPADDW  (SI), Y0, Y0
+	VMOVDQU Y0, (SI)
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $16, CX
+	JMP     loop16
+
+tail:
+	VZEROUPPER
+
+tail1:
+	TESTQ   CX, CX
+	JZ      done
+	MOVWLZX (DI), R8
+	IMULL   AX, R8
+	ADDW    R8, (SI)
+	ADDQ    $2, SI
+	ADDQ    $2, DI
+	DECQ    CX
+	JMP     tail1
+
+done:
+	RET
+
+// func axpySSE2(y []uint16, c uint16, x []uint16)
+TEXT ·axpySSE2(SB), NOSPLIT, $0-56
+	MOVQ    y_base+0(FP), SI
+	MOVQ    y_len+8(FP), CX
+	MOVWLZX c+24(FP), AX
+	MOVQ    x_base+32(FP), DI
+	MOVQ    AX, X2
+	PSHUFLW $0, X2, X2
+	PSHUFD  $0, X2, X2
+
+loop8:
+	CMPQ   CX, $8
+	JB     tail
+	MOVOU  (DI), X0
+	PMULLW X2, X0
+	MOVOU  (SI), X1
+	PADDW  X1, X0
+	MOVOU  X0, (SI)
+	ADDQ   $16, SI
+	ADDQ   $16, DI
+	SUBQ   $8, CX
+	JMP    loop8
+
+tail:
+	TESTQ   CX, CX
+	JZ      done
+	MOVWLZX (DI), R8
+	IMULL   AX, R8
+	ADDW    R8, (SI)
+	ADDQ    $2, SI
+	ADDQ    $2, DI
+	DECQ    CX
+	JMP     tail
+
+done:
+	RET
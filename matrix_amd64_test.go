@@ -0,0 +1,73 @@
+//go:build amd64 && !purego
+
+package frodo
+
+import (
+	"math/rand"
+	"testing"
+
+	"golang.org/x/sys/cpu"
+)
+
+// testing the assembly kernels of matrix_amd64.s against dotGeneric & axpyGeneric
+
+func TestKernelsAsm(t *testing.T) {
+
+	defer func(avx2 bool) { useAVX2 = avx2 }(useAVX2)
+
+	rnd := rand.New(rand.NewSource(3))
+	random := func(n int) []uint16 {
+		v := make([]uint16, n)
+		for i := range v {
+			v[i] = uint16(rnd.Intn(1 << 16))
+		}
+		return v
+	}
+
+	kernels := []bool{false}
+	if cpu.X86.HasAVX2 {
+		kernels = append(kernels, true)
+	}
+	for _, avx2 := range kernels {
+		useAVX2 = avx2
+		for n := 0; n < 80; n++ { // every tail length
+			a, b, y := random(n), random(n+3), random(n)
+			if got, want := dot(a, b), dotGeneric(a, b); got != want {
+				t.Fatalf("matrix_amd64_test.go/TestKernelsAsm: avx2 %v dot of %d entries = %d, want %d", avx2, n, got, want)
+			}
+			c, want := uint16(rnd.Intn(1<<16)), append([]uint16(nil), y...)
+			axpy(y, c, b)
+			axpyGeneric(want, c, b)
+			for j := range y {
+				if y[j] != want[j] {
+					t.Fatalf("matrix_amd64_test.go/TestKernelsAsm: avx2 %v axpy of %d entries differs at %d", avx2, n, j)
+				}
+			}
+		}
+
+		for _, param := range []*Parameters{Frodo640(), Frodo976(), Frodo1344()} {
+			matrix := func(n1, n2 int) *matrix { return &matrix{rows: n1, cols: n2, stride: n2, data: random(n1 * n2)} }
+			S, A, E := matrix(param.m, param.no), matrix(param.no, param.no), matrix(param.m, param.no)
+			St, B, Ebar := matrix(param.n, param.no), matrix(param.no, param.n), matrix(param.no, param.n)
+
+			SA, AS, SB := mulAddAB(S, A, E), mulAddABt(A, St, Ebar), mulAddAB(S, B, nil)
+
+			if ctEqual(SA, naiveMulAdd(S, A, E)) != 1 || ctEqual(AS, naiveMulAdd(A, St.transpose(), Ebar)) != 1 ||
+				ctEqual(SB, naiveMulAdd(S, B, newMatrix(param.m, param.n))) != 1 {
+				t.Errorf("matrix_amd64_test.go/TestKernelsAsm: avx2 %v %s products differ from the Go reference", avx2, param.Name())
+			}
+		}
+	}
+}
+
+// naiveMulAdd returns A·B + E (mod 2^16) using only dotGeneric
+func naiveMulAdd(A, B, E *matrix) *matrix {
+
+	C, Bt := newMatrix(A.rows, B.cols), B.transpose()
+	for i := 0; i < C.rows; i++ {
+		for j := 0; j < C.cols; j++ {
+			C.data[i*C.stride+j] = dotGeneric(A.row(i), Bt.row(j)) + E.data[i*E.stride+j]
+		}
+	}
+	return C
+}
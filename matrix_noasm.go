@@ -0,0 +1,13 @@
+//go:build !amd64 || purego
+
+package frodo
+
+// dot returns Σ a[j]·b[j] (mod 2^16), len(b) ≥ len(a)
+func dot(a, b []uint16) uint16 {
+	return dotGeneric(a, b)
+}
+
+// axpy adds c·x to y (mod 2^16), len(x) ≥ len(y)
+func axpy(y []uint16, c uint16, x []uint16) {
+	axpyGeneric(y, c, x)
+}
@@ -1,77 +1,160 @@
 package frodo
 
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
 // PKE interface
 type PKE interface {
-	KeyGen() (pk *PublicKey, sk *SecretKey)        // returns key pair sructure
-	Enc(message []byte, pk *PublicKey) *CipherText // returns CipherText structure which contains C = (C1, C2)
-	Dec(cipher *CipherText, sk *SecretKey) []byte  // returns decrypted with secret key ciphertext
+	KeyGen() (pk *PublicKey, sk *SecretKey)                                                  // returns key pair sructure
+	KeyGenFrom(random io.Reader) (pk *PublicKey, sk *SecretKey, err error)                   // returns key pair structure using random
+	Enc(message []byte, pk *PublicKey) *CipherText                                           // returns CipherText structure which contains C = (C1, C2)
+	EncFrom(random io.Reader, message []byte, pk *PublicKey) (cipher *CipherText, err error) // returns CipherText structure using random
+	Dec(cipher *CipherText, sk *SecretKey) []byte                                            // returns decrypted with secret key ciphertext
+	DecChecked(cipher *CipherText, sk *SecretKey) ([]byte, error)                            // returns decrypted with secret key validated ciphertext
 }
 
 // PublicKey structure contains seedA uniform bit string and n-by-m public matrix B є Zq
 type PublicKey struct {
 	SeedA []byte     // uniform string
 	B     [][]uint16 // matrix є Zq
+
+	param *Parameters // parameter set of the key
 }
 
 // SecretKey structure contains matrix S є Zq
 type SecretKey struct {
 	S [][]uint16 // matrix є Zq
+
+	param *Parameters // parameter set of the key
 }
 
 // CipherText structure contains matrices C1 and C2
 type CipherText struct {
 	C1, C2 [][]uint16
+
+	param *Parameters // parameter set of the ciphertext
 }
 
-// KeyGen genere key pair for chosen parameters
+// KeyGen genere key pair for chosen parameters using crypto/rand,
+// it panics if the system randomness source fails
 func (param *Parameters) KeyGen() (pk *PublicKey, sk *SecretKey) {
 
-	pk, sk = new(PublicKey), new(SecretKey)
-	pk.SeedA = uniform(param.lseedA)
-	rLen, seedSE := 2*param.no*param.n*param.lenX, uniform((param.lseedSE)+1)
+	pk, sk, err := param.KeyGenFrom(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// KeyGenFrom genere key pair for chosen parameters,
+// seeds are read from random
+func (param *Parameters) KeyGenFrom(random io.Reader) (pk *PublicKey, sk *SecretKey, err error) {
+
+	randomness, err := uniform(random, param.lseedA+param.lseedSE)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pk, sk = &PublicKey{param: param}, &SecretKey{param: param}
+	pk.SeedA = randomness[:param.lseedA]
+	rLen, seedSE := 2*param.no*param.n*param.lenX, append([]byte{0x5F}, randomness[param.lseedA:]...)
 
-	seedSE[0] = 0x5F
 	r := param.shake(seedSE, rLen)
 
 	rLen /= 2
-	A := param.Gen(pk.SeedA)
-	sk.S = param.SampleMatrix(r[:rLen], param.no, param.n)
-	E := param.SampleMatrix(r[rLen:], param.no, param.n)
-	pk.B = param.mulAddMatrices(A, sk.S, E)
+	St := param.sampleSecret(r[:rLen])
+	E := param.sampleMatrix(r[rLen:], param.no, param.n)
+	sk.S = St.transpose().slices()
+	pk.B = param.reduce(param.mulAddAS(pk.SeedA, St, E)).slices() // B = A*S + E
 
-	return
+	return pk, sk, nil
 }
 
 // Enc encrypts message for chosen parameters, using public key structure
-// returns C = (C1, C2); C1 = S1*A + E1,
-// C2 = V + M = S1*B + E2 + M = S1*A*S + S1*E + E2 + M
+// and crypto/rand, it panics if the system randomness source fails
 func (param *Parameters) Enc(message []byte, pk *PublicKey) *CipherText {
-	seedSE := uniform(param.lseedSE + 1)
-	A, rLen := param.Gen(pk.SeedA), (2*param.no+param.n)*param.m*param.lenX
 
-	seedSE[0] = 0x96
+	cipher, err := param.EncFrom(rand.Reader, message, pk)
+	if err != nil {
+		panic(err)
+	}
+	return cipher
+}
+
+// EncFrom encrypts message of lenM bytes for chosen parameters, using public key structure
+// and seedSE read from random; message and pk are validated first
+func (param *Parameters) EncFrom(random io.Reader, message []byte, pk *PublicKey) (*CipherText, error) {
+
+	randomness, err := uniform(random, param.lseedSE)
+	if err != nil {
+		return nil, err
+	}
+	return param.EncryptWithSeed(message, pk, randomness)
+}
+
+// EncryptWithSeed encrypts message of lenM bytes deterministically: seedSE of lseedSE bytes
+// replaces the randomness of Enc. It is meant for testing and protocols with explicit coins,
+// reusing seedSE for another message breaks the security of both ciphertexts
+// returns C = (C1, C2); C1 = S1*A + E1,
+// C2 = V + M = S1*B + E2 + M = S1*A*S + S1*E + E2 + M
+func (param *Parameters) EncryptWithSeed(message []byte, pk *PublicKey, seedSE []byte) (*CipherText, error) {
+
+	if len(message) != param.lenM {
+		return nil, fmt.Errorf("%w: message must be %d bytes, got %d", ErrInvalidMessage, param.lenM, len(message))
+	}
+	if err := param.ValidatePublicKey(pk); err != nil {
+		return nil, err
+	}
+	if len(seedSE) != param.lseedSE {
+		return nil, fmt.Errorf("frodo: seedSE must be %d bytes, got %d", param.lseedSE, len(seedSE))
+	}
+
+	seedSE = append([]byte{0x96}, seedSE...)
+	rLen := (2*param.no + param.n) * param.m * param.lenX
 	r := param.shake(seedSE, rLen)
 
 	rLen = param.m * param.no * param.lenX
-	S1 := param.SampleMatrix(r[:rLen], param.m, param.no)
-	E1 := param.SampleMatrix(r[rLen:2*rLen], param.m, param.no)
-	E2 := param.SampleMatrix(r[2*rLen:], param.m, param.n)
-	V := param.mulAddMatrices(S1, pk.B, E2)
+	S1 := param.sampleMatrix(r[:rLen], param.m, param.no)
+	E1 := param.sampleMatrix(r[rLen:2*rLen], param.m, param.no)
+	E2 := param.sampleMatrix(r[2*rLen:], param.m, param.n)
+	V := mulAddAB(S1, matrixOf(pk.B), E2)
 
-	cipher := new(CipherText)
-	cipher.C1 = param.mulAddMatrices(S1, A, E1)             // C1 = S1*A + E1
-	cipher.C2 = param.sumMatrices(V, param.Encode(message)) // C2 = V + M = S1*B + E2 + M = S1*A*S + S1*E + E2 + M
+	cipher := &CipherText{param: param}
+	cipher.C1 = param.reduce(param.mulAddSA(S1, pk.SeedA, E1)).slices() // C1 = S1*A + E1
+	cipher.C2 = param.reduce(add(V, param.encode(message))).slices()    // C2 = V + M = S1*B + E2 + M = S1*A*S + S1*E + E2 + M
 
-	return cipher
+	return cipher, nil
+}
+
+// Dec returns decrypted with secret key cihertext,
+// it panics on malformed cipher or sk, use DecChecked for untrusted input
+func (param *Parameters) Dec(cipher *CipherText, sk *SecretKey) []byte {
+
+	message, err := param.DecChecked(cipher, sk)
+	if err != nil {
+		panic(err)
+	}
+	return message
 }
 
-// Dec returns decrypted with secret key cihertext
+// DecChecked returns decrypted with secret key validated cihertext
 // with error S1*E + E2 − E1*S, that cleans up using Decode
 // proved by lemma 2.18 [FKEM]
-func (param *Parameters) Dec(cipher *CipherText, sk *SecretKey) []byte {
+func (param *Parameters) DecChecked(cipher *CipherText, sk *SecretKey) ([]byte, error) {
 
-	M := param.subMatrices(cipher.C2, param.mulMatrices(cipher.C1, sk.S)) // M = C2 - C1*S = Enc(message) + S1*E + E2 - E1*S
-	message := param.Decode(M)
+	if err := param.ValidateSecretKey(sk); err != nil {
+		return nil, err
+	}
+	if err := param.ValidateCipherText(cipher); err != nil {
+		return nil, err
+	}
 
-	return message
+	C1S := mulAddAB(matrixOf(cipher.C1), matrixOf(sk.S), nil)
+	M := sub(matrixOf(cipher.C2), C1S) // M = C2 - C1*S = Enc(message) + S1*E + E2 - E1*S
+	message := param.decode(M)
+
+	return message, nil
 }
@@ -0,0 +1,216 @@
+package frodo
+
+import (
+	"container/list"
+	"crypto/rand"
+	"io"
+	"sync"
+)
+
+// expandedKey holds the parts of a key used by the arithmetic of Encaps and Decaps:
+// B unpacked, pkh and, for secret keys, s and S. A is nil unless the key is prepared,
+// then it is generated row by row from seedA on every operation
+type expandedKey struct {
+	seedA []byte
+	A, B  *matrix
+	pkh   []byte
+	seedS []byte  // s, secret keys only
+	S     *matrix // S є Zq (no*n), secret keys only
+}
+
+// publicKeyHash returns pkh = SHAKE(seedA || b, lenpkh)
+func (param *Parameters) publicKeyHash(seedA, b []byte) []byte {
+
+	var pk []byte
+	pk = append(pk, seedA...)
+	pk = append(pk, b...)
+	return param.shake(pk, param.lenpkh)
+}
+
+// expandPublicKey returns the expanded validated public key without A
+func (param *Parameters) expandPublicKey(pk *EncapsPublicKey) *expandedKey {
+	return &expandedKey{
+		seedA: pk.SeedA,
+		B:     param.unpack(pk.B, param.no, param.n),
+		pkh:   param.publicKeyHash(pk.SeedA, pk.B),
+	}
+}
+
+// expandSecretKey returns the expanded validated secret key without A
+func (param *Parameters) expandSecretKey(sk *EncapsSecretKey) *expandedKey {
+	return &expandedKey{
+		seedA: sk.SeedA,
+		B:     param.unpack(sk.B, param.no, param.n),
+		pkh:   sk.Pkh,
+		seedS: sk.SeedS,
+		S:     matrixOf(sk.S),
+	}
+}
+
+// mulAddSKeyTo sets C to S·A + E (mod 2^16) using the expanded A of key if any
+func (param *Parameters) mulAddSKeyTo(C, S *matrix, key *expandedKey, E *matrix, s *Scratch) *matrix {
+
+	if key.A != nil {
+		return mulAddABTo(C, S, key.A, E)
+	}
+	return param.mulAddSATo(C, S, key.seedA, E, s)
+}
+
+// PreparedPublicKey is a validated public key with A expanded, B unpacked and pkh computed
+// once for repeated encapsulations. It is immutable and safe for concurrent use;
+// A takes 2n² bytes, 3.6 MB for n = 1344
+type PreparedPublicKey struct {
+	param *Parameters
+	pk    *EncapsPublicKey
+	key   *expandedKey
+}
+
+// PreparedSecretKey is a validated secret key with A expanded and B, S unpacked
+// once for repeated decapsulations. It is immutable and safe for concurrent use
+type PreparedSecretKey struct {
+	param *Parameters
+	key   *expandedKey
+}
+
+// PreparePublicKey validates pk and returns its prepared form
+func (param *Parameters) PreparePublicKey(pk *EncapsPublicKey) (*PreparedPublicKey, error) {
+
+	if err := param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, err
+	}
+	pk = &EncapsPublicKey{SeedA: append([]byte(nil), pk.SeedA...), B: append([]byte(nil), pk.B...), param: param}
+	key := param.expandPublicKey(pk)
+	key.A = param.genMatrix(pk.SeedA)
+	return &PreparedPublicKey{param: param, pk: pk, key: key}, nil
+}
+
+// PrepareSecretKey validates sk and returns its prepared form
+func (param *Parameters) PrepareSecretKey(sk *EncapsSecretKey) (*PreparedSecretKey, error) {
+
+	if err := param.ValidateEncapsSecretKey(sk); err != nil {
+		return nil, err
+	}
+	key := param.expandSecretKey(sk)
+	key.seedA = append([]byte(nil), key.seedA...)
+	key.pkh = append([]byte(nil), key.pkh...)
+	key.seedS = append([]byte(nil), key.seedS...)
+	key.A = param.genMatrix(key.seedA)
+	return &PreparedSecretKey{param: param, key: key}, nil
+}
+
+// PublicKey returns a copy of the public key
+func (pk *PreparedPublicKey) PublicKey() *EncapsPublicKey {
+	return &EncapsPublicKey{SeedA: append([]byte(nil), pk.pk.SeedA...), B: append([]byte(nil), pk.pk.B...), param: pk.param}
+}
+
+// Pkh returns a copy of pkh = SHAKE(seedA || b), the hash of the public key
+func (pk *PreparedPublicKey) Pkh() []byte {
+	return append([]byte(nil), pk.key.pkh...)
+}
+
+// Encaps returns encapsulated ciphertext and secret ss using crypto/rand,
+// it panics if the system randomness source fails
+func (pk *PreparedPublicKey) Encaps() (ct *EncapsCipherText, ss []byte) {
+
+	ct, ss, err := pk.EncapsFrom(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// EncapsFrom returns encapsulated ciphertext and secret ss,
+// message m || salt is read from random
+func (pk *PreparedPublicKey) EncapsFrom(random io.Reader) (ct *EncapsCipherText, ss []byte, err error) {
+
+	randomness, err := uniform(random, pk.param.EncapsulationSeedSize())
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, ss = pk.param.encapsKey(pk.key, randomness, pk.param.NewScratch())
+	return ct, ss, nil
+}
+
+// DecapsChecked returns secret ss from ciphertext, ct is validated before any arithmetic
+func (sk *PreparedSecretKey) DecapsChecked(ct *EncapsCipherText) (ss []byte, err error) {
+
+	if err := sk.param.ValidateEncapsCipherText(ct); err != nil {
+		return nil, err
+	}
+	return sk.param.NewScratch().decaps(nil, sk.key, ct.C1, ct.C2, ct.Salt), nil
+}
+
+// Decapsulate returns secret ss from packed ciphertext
+func (sk *PreparedSecretKey) Decapsulate(ciphertext []byte) (sharedKey []byte, err error) {
+
+	ct, err := sk.param.UnmarshalEncapsCipherText(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return sk.DecapsChecked(ct)
+}
+
+// KeyCache is an LRU cache of at most size prepared public keys keyed by pkh,
+// it is safe for concurrent use
+type KeyCache struct {
+	param *Parameters
+	size  int
+
+	mu    sync.Mutex
+	order *list.List               // most recently used first, values are *PreparedPublicKey
+	keys  map[string]*list.Element // by pkh
+}
+
+// NewKeyCache returns an empty cache of at most size prepared public keys, size ≥ 1
+func (param *Parameters) NewKeyCache(size int) *KeyCache {
+
+	if size < 1 {
+		size = 1
+	}
+	return &KeyCache{param: param, size: size, order: list.New(), keys: make(map[string]*list.Element)}
+}
+
+// Get returns the prepared form of pk, it is prepared and cached on a miss,
+// evicting the least recently used key of a full cache
+func (c *KeyCache) Get(pk *EncapsPublicKey) (*PreparedPublicKey, error) {
+
+	if err := c.param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, err
+	}
+	pkh := string(c.param.publicKeyHash(pk.SeedA, pk.B))
+
+	c.mu.Lock()
+	if e, ok := c.keys[pkh]; ok {
+		c.order.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*PreparedPublicKey), nil
+	}
+	c.mu.Unlock()
+
+	prepared, err := c.param.PreparePublicKey(pk) // outside the lock, concurrent misses may prepare pk twice
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.keys[pkh]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*PreparedPublicKey), nil
+	}
+	c.keys[pkh] = c.order.PushFront(prepared)
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.keys, string(last.Value.(*PreparedPublicKey).key.pkh))
+	}
+	return prepared, nil
+}
+
+// Len returns the number of cached keys
+func (c *KeyCache) Len() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
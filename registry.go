@@ -0,0 +1,101 @@
+package frodo
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry of the parameter sets by canonical name and OID. The names are those of
+// the specifications, like FrodoKEM-640-SHAKE or FrodoKEM-976-AES-2019. No OID is built in:
+// the OIDs of the Open Quantum Safe provider and of the IETF drafts are provisional and
+// not final, so LookupOID knows no OID until an application binds the ones it uses with RegisterOID
+var registry = struct {
+	sync.RWMutex
+	byName map[string]*Parameters // by lower-case name
+	byOID  map[string]*Parameters // by dotted OID
+	oids   map[string]asn1.ObjectIdentifier
+}{
+	byName: make(map[string]*Parameters),
+	byOID:  make(map[string]*Parameters),
+	oids:   make(map[string]asn1.ObjectIdentifier), // by name
+}
+
+func init() {
+
+	for _, param := range []*Parameters{
+		frodo640, frodo976, frodo1344, frodo640AES, frodo976AES, frodo1344AES,
+		frodoKEM640SHAKE, frodoKEM976SHAKE, frodoKEM1344SHAKE, frodoKEM640AES, frodoKEM976AES, frodoKEM1344AES,
+		eFrodoKEM640SHAKE, eFrodoKEM976SHAKE, eFrodoKEM1344SHAKE, eFrodoKEM640AES, eFrodoKEM976AES, eFrodoKEM1344AES,
+	} {
+		registry.byName[strings.ToLower(param.name)] = param
+	}
+}
+
+// Lookup returns the parameter set of the canonical name, the case is ignored
+func Lookup(name string) (*Parameters, error) {
+
+	registry.RLock()
+	defer registry.RUnlock()
+	if param, ok := registry.byName[strings.ToLower(name)]; ok {
+		return param, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownParameters, name)
+}
+
+// LookupOID returns the parameter set registered for oid with RegisterOID
+func LookupOID(oid asn1.ObjectIdentifier) (*Parameters, error) {
+
+	registry.RLock()
+	defer registry.RUnlock()
+	if param, ok := registry.byOID[oid.String()]; ok {
+		return param, nil
+	}
+	return nil, fmt.Errorf("%w: OID %s", ErrUnknownParameters, oid)
+}
+
+// RegisterOID binds oid to the registered parameter set of the same name as param,
+// a set has at most one OID and an OID names one set; registering the same pair twice is allowed
+func RegisterOID(oid asn1.ObjectIdentifier, param *Parameters) error {
+
+	registry.Lock()
+	defer registry.Unlock()
+	name := strings.ToLower(param.name)
+	registered, ok := registry.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownParameters, param.name)
+	}
+	if other, ok := registry.byOID[oid.String()]; ok && other != registered {
+		return fmt.Errorf("frodo: OID %s is registered for %s", oid, other.name)
+	}
+	if old, ok := registry.oids[name]; ok && !old.Equal(oid) {
+		return fmt.Errorf("frodo: %s has the OID %s", param.name, old)
+	}
+	registry.byOID[oid.String()] = registered
+	registry.oids[name] = append(asn1.ObjectIdentifier(nil), oid...)
+	return nil
+}
+
+// Names returns the sorted canonical names of the registered parameter sets
+func Names() []string {
+
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.byName))
+	for _, param := range registry.byName {
+		names = append(names, param.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OID returns the OID registered for the parameter set with RegisterOID, if any
+func (param *Parameters) OID() (asn1.ObjectIdentifier, bool) {
+
+	registry.RLock()
+	defer registry.RUnlock()
+	oid, ok := registry.oids[strings.ToLower(param.name)]
+	return append(asn1.ObjectIdentifier(nil), oid...), ok
+}
@@ -0,0 +1,228 @@
+package frodo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+)
+
+// Scheme interface of a key encapsulation mechanism with introspectable sizes and names,
+// keys and ciphertexts are handled as byte strings of the specification (see encoding.go)
+type Scheme interface {
+	Name() string               // name of the parameter set, for example FrodoKEM-640-AES
+	PublicKeySize() int         // byte length of packed public keys
+	PrivateKeySize() int        // byte length of packed secret keys
+	CiphertextSize() int        // byte length of packed ciphertexts
+	SharedKeySize() int         // byte length of shared secrets
+	SeedSize() int              // byte length of seeds of DeriveKeyPair
+	EncapsulationSeedSize() int // byte length of coins of EncapsulateDeterministic
+
+	GenerateKeyPair() (*EncapsPublicKey, *EncapsSecretKey, error)                       // returns key pair using crypto/rand
+	DeriveKeyPair(seed []byte) (*EncapsPublicKey, *EncapsSecretKey, error)              // returns key pair derived from seed
+	Encapsulate(pk *EncapsPublicKey) (ct, ss []byte, err error)                         // using pk, returns packed ct and secret ss
+	EncapsulateDeterministic(pk *EncapsPublicKey, mu []byte) (ct, ss []byte, err error) // using pk and coins mu, returns packed ct and secret ss
+	Decapsulate(sk *EncapsSecretKey, ct []byte) (ss []byte, err error)                  // using sk, returns secret ss from packed ct
+	UnmarshalBinaryPublicKey(data []byte) (*EncapsPublicKey, error)                     // decodes packed public key
+	UnmarshalBinaryPrivateKey(data []byte) (*EncapsSecretKey, error)                    // decodes packed secret key
+}
+
+var _ Scheme = (*Parameters)(nil)
+
+// SharedKeySize returns the byte length of shared secrets ss
+func (param *Parameters) SharedKeySize() int {
+	return param.lenss
+}
+
+// SeedSize returns the byte length of master seeds of DeriveKeyPair,
+// it is the byte length of s, the security level of the parameter set
+func (param *Parameters) SeedSize() int {
+	return param.lens
+}
+
+// EncapsulationSeedSize returns the byte length of coins of EncapsulateDeterministic, m || salt
+func (param *Parameters) EncapsulationSeedSize() int {
+	return param.lenM + param.lenSalt
+}
+
+// GenerateKeyPair returns key pair using crypto/rand
+func (param *Parameters) GenerateKeyPair() (*EncapsPublicKey, *EncapsSecretKey, error) {
+	return param.EncapsKeyGenFrom(rand.Reader)
+}
+
+// DeriveKeyPair returns key pair derived from the master seed of SeedSize bytes:
+// s || seedSE || z = SHAKE(seed) are the seeds EncapsKeyGen draws from its randomness source;
+// the secret key keeps seed, so it can be stored with MarshalSeed
+func (param *Parameters) DeriveKeyPair(seed []byte) (*EncapsPublicKey, *EncapsSecretKey, error) {
+
+	if len(seed) != param.SeedSize() {
+		return nil, nil, fmt.Errorf("%w: %s seed must be %d bytes, got %d", ErrInvalidSecretKey, param.name, param.SeedSize(), len(seed))
+	}
+
+	randomness := param.shake(seed, param.lens+param.lseedSE+param.lenz)
+	pk, sk, err := param.EncapsKeyGenFrom(bytes.NewReader(randomness))
+	if err != nil {
+		return nil, nil, err
+	}
+	sk.seed = append([]byte(nil), seed...)
+	return pk, sk, nil
+}
+
+// Encapsulate returns packed ciphertext and secret ss using public key and crypto/rand
+func (param *Parameters) Encapsulate(pk *EncapsPublicKey) (ct, ss []byte, err error) {
+
+	c, ss, err := param.EncapsFrom(rand.Reader, pk)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, _ = c.MarshalBinary()
+	return ct, ss, nil
+}
+
+// EncapsulateBatch returns count packed ciphertexts and secrets ss to public key using crypto/rand,
+// the encapsulations are independent but share one pass over A, see EncapsBatchFrom
+func (param *Parameters) EncapsulateBatch(pk *EncapsPublicKey, count int) (cts, sss [][]byte, err error) {
+
+	c, sss, err := param.EncapsBatchFrom(rand.Reader, pk, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	cts = make([][]byte, len(c))
+	for i := range c {
+		cts[i], _ = c[i].MarshalBinary()
+	}
+	return cts, sss, nil
+}
+
+// EncapsulateDeterministic returns packed ciphertext and secret ss using public key and
+// coins mu = m || salt of EncapsulationSeedSize bytes instead of fresh randomness.
+// It is meant for testing and protocols with explicit coins: coins must be uniformly random
+// and never reused, otherwise the shared secret is not secret
+func (param *Parameters) EncapsulateDeterministic(pk *EncapsPublicKey, mu []byte) (ct, ss []byte, err error) {
+
+	if err := param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, nil, err
+	}
+	if len(mu) != param.EncapsulationSeedSize() {
+		return nil, nil, fmt.Errorf("frodo: %s coins must be %d bytes, got %d", param.name, param.EncapsulationSeedSize(), len(mu))
+	}
+
+	c, ss := param.encaps(pk, mu)
+	ct, _ = c.MarshalBinary()
+	return ct, ss, nil
+}
+
+// Decapsulate returns secret ss from packed ciphertext using secret key
+func (param *Parameters) Decapsulate(sk *EncapsSecretKey, ct []byte) (ss []byte, err error) {
+
+	c, err := param.UnmarshalEncapsCipherText(ct)
+	if err != nil {
+		return nil, err
+	}
+	return param.DecapsChecked(c, sk)
+}
+
+// UnmarshalBinaryPublicKey decodes packed public key, see UnmarshalEncapsPublicKey
+func (param *Parameters) UnmarshalBinaryPublicKey(data []byte) (*EncapsPublicKey, error) {
+	return param.UnmarshalEncapsPublicKey(data)
+}
+
+// UnmarshalBinaryPrivateKey decodes packed secret key, see UnmarshalEncapsSecretKey
+func (param *Parameters) UnmarshalBinaryPrivateKey(data []byte) (*EncapsSecretKey, error) {
+	return param.UnmarshalEncapsSecretKey(data)
+}
+
+// Scheme returns the parameter set of the key, nil if it is unknown
+func (pk *EncapsPublicKey) Scheme() Scheme {
+
+	if pk.param == nil {
+		return nil
+	}
+	return pk.param
+}
+
+// Bytes returns the packed public key seedA || b
+func (pk *EncapsPublicKey) Bytes() []byte {
+
+	b, _ := pk.MarshalBinary()
+	return b
+}
+
+// Encapsulate returns secret ss and packed ciphertext using crypto/rand,
+// like crypto/mlkem; it panics if pk does not know its parameter set
+func (pk *EncapsPublicKey) Encapsulate() (sharedKey, ciphertext []byte) {
+
+	if pk.param == nil {
+		panic(errUnboundKey)
+	}
+	ct, ss, err := pk.param.Encapsulate(pk)
+	if err != nil {
+		panic(err)
+	}
+	return ss, ct
+}
+
+// Scheme returns the parameter set of the key, nil if it is unknown
+func (sk *EncapsSecretKey) Scheme() Scheme {
+
+	if sk.param == nil {
+		return nil
+	}
+	return sk.param
+}
+
+// Bytes returns the packed secret key s || seedA || b || Sᵀ || pkh
+func (sk *EncapsSecretKey) Bytes() []byte {
+
+	b, _ := sk.MarshalBinary()
+	return b
+}
+
+// EncapsulationKey returns the public key of sk
+func (sk *EncapsSecretKey) EncapsulationKey() *EncapsPublicKey {
+	return &EncapsPublicKey{SeedA: sk.SeedA, B: sk.B, param: sk.param}
+}
+
+// Decapsulate returns secret ss from packed ciphertext, like crypto/mlkem
+func (sk *EncapsSecretKey) Decapsulate(ciphertext []byte) (sharedKey []byte, err error) {
+
+	if sk.param == nil {
+		return nil, errUnboundKey
+	}
+	return sk.param.Decapsulate(sk, ciphertext)
+}
+
+// Scheme returns the parameter set of the ciphertext, nil if it is unknown
+func (ct *EncapsCipherText) Scheme() Scheme {
+
+	if ct.param == nil {
+		return nil
+	}
+	return ct.param
+}
+
+// Scheme returns the parameter set of the key, nil if it is unknown
+func (pk *PublicKey) Scheme() Scheme {
+
+	if pk.param == nil {
+		return nil
+	}
+	return pk.param
+}
+
+// Scheme returns the parameter set of the key, nil if it is unknown
+func (sk *SecretKey) Scheme() Scheme {
+
+	if sk.param == nil {
+		return nil
+	}
+	return sk.param
+}
+
+// Scheme returns the parameter set of the ciphertext, nil if it is unknown
+func (cipher *CipherText) Scheme() Scheme {
+
+	if cipher.param == nil {
+		return nil
+	}
+	return cipher.param
+}
@@ -0,0 +1,213 @@
+package frodo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Scratch is the working memory of encapsulations and decapsulations of one parameter set:
+// hash states, generators of A, noise and matrices are allocated once by NewScratch and reused,
+// so that EncapsulateTo and DecapsulateTo into buffers with enough capacity do not allocate.
+// It is not safe for concurrent use, use one Scratch per goroutine (or a sync.Pool);
+// parameter sets WithConcurrency above 1 still allocate their goroutines
+type Scratch struct {
+	param *Parameters
+
+	h         sha3.ShakeHash // SHAKE of the parameter set
+	shakeRows *shakeRows     // generators of A, reset for every seedA
+	aesRows   *aesRows
+	chunk     []uint16 // rowsPerChunk rows of A
+
+	coins  []byte // m || salt
+	seed   []byte // seedSE || k
+	seedSE []byte // 0x96 || seedSE
+	r      []byte // noise of S', E1 and E2
+	m      []byte // decoded message
+	k      []byte // k' or s, selected in constant time
+	pkh    []byte
+
+	S1, E1, B1, B2, Bp *matrix // m-by-no matrices
+	E2, V, C, C1       *matrix // m-by-n matrices
+	B, S               *matrix // no-by-n matrices of keys
+	key                expandedKey
+}
+
+// NewScratch returns the working memory of the parameter set, see Scratch
+func (param *Parameters) NewScratch() *Scratch {
+
+	return &Scratch{
+		param:  param,
+		h:      param.newShake(),
+		chunk:  make([]uint16, rowsPerChunk*param.no),
+		coins:  make([]byte, 0, param.EncapsulationSeedSize()),
+		seed:   make([]byte, 0, param.lseedSE+param.lenk),
+		seedSE: make([]byte, 0, 1+param.lseedSE),
+		r:      make([]byte, 0, (2*param.no+param.n)*param.m*param.lenX),
+		m:      make([]byte, 0, param.lenM),
+		k:      make([]byte, 0, param.lens),
+		pkh:    make([]byte, 0, param.lenpkh),
+		S1:     newMatrix(param.m, param.no),
+		E1:     newMatrix(param.m, param.no),
+		B1:     newMatrix(param.m, param.no),
+		B2:     newMatrix(param.m, param.no),
+		Bp:     newMatrix(param.m, param.no),
+		E2:     newMatrix(param.m, param.n),
+		V:      newMatrix(param.m, param.n),
+		C:      newMatrix(param.m, param.n),
+		C1:     newMatrix(param.m, param.n),
+		B:      newMatrix(param.no, param.n),
+		S:      newMatrix(param.no, param.n),
+	}
+}
+
+// EncapsulateTo appends a packed ciphertext to ctDst and its secret ss to ssDst using public key
+// and crypto/rand, and returns the extended slices; it does not allocate if ctDst and ssDst
+// have CiphertextSize and SharedKeySize bytes of spare capacity
+func (s *Scratch) EncapsulateTo(ctDst, ssDst []byte, pk *EncapsPublicKey) (ct, ss []byte, err error) {
+
+	if err := s.param.ValidateEncapsPublicKey(pk); err != nil {
+		return nil, nil, err
+	}
+	s.coins = s.coins[:s.param.EncapsulationSeedSize()]
+	if _, err := io.ReadFull(rand.Reader, s.coins); err != nil {
+		return nil, nil, fmt.Errorf("frodo: reading randomness: %w", err)
+	}
+	ct, ss = s.encaps(ctDst, ssDst, s.expandPublicKey(pk), s.coins)
+	return ct, ss, nil
+}
+
+// DecapsulateTo appends the secret ss of packed ciphertext ct to ssDst using secret key,
+// and returns the extended slice; it does not allocate if ssDst has SharedKeySize bytes
+// of spare capacity
+func (s *Scratch) DecapsulateTo(ssDst []byte, sk *EncapsSecretKey, ct []byte) ([]byte, error) {
+
+	param := s.param
+	if err := param.ValidateEncapsSecretKey(sk); err != nil {
+		return nil, err
+	}
+	if err := checkLen(ErrInvalidCiphertext, "ciphertext", ct, param.CiphertextSize()); err != nil {
+		return nil, err
+	}
+	c1, c2 := param.d*param.m*param.no/8, param.d*param.m*param.n/8
+	return s.decaps(ssDst, s.expandSecretKey(sk), ct[:c1], ct[c1:c1+c2], ct[c1+c2:]), nil
+}
+
+// shake appends length bytes of SHAKE(parts[0] || parts[1] || ...) to dst
+func (s *Scratch) shake(dst []byte, length int, parts ...[]byte) []byte {
+
+	s.h.Reset()
+	for _, p := range parts {
+		s.h.Write(p)
+	}
+	s.h.Read(grow(&dst, length))
+	return dst
+}
+
+// rows returns the generator of the rows of A for seedA, reusing the ones of s
+func (s *Scratch) rows(seedA []byte) rowsGenerator {
+
+	switch s.param.gen {
+	case genAES128:
+		if s.aesRows == nil {
+			s.aesRows = newAESRows(s.param, seedA)
+		} else {
+			s.aesRows.reset(seedA)
+		}
+		return s.aesRows
+	case genLegacy:
+		return s.param.newRows(seedA)
+	default:
+		if s.shakeRows == nil {
+			s.shakeRows = newShakeRows(s.param, seedA)
+		} else {
+			s.shakeRows.reset(seedA)
+		}
+		return s.shakeRows
+	}
+}
+
+// expandPublicKey returns the validated public key expanded into s, without A
+func (s *Scratch) expandPublicKey(pk *EncapsPublicKey) *expandedKey {
+
+	s.param.unpackEntries(s.B.data, pk.B)
+	s.pkh = s.shake(s.pkh[:0], s.param.lenpkh, pk.SeedA, pk.B)
+	s.key = expandedKey{seedA: pk.SeedA, B: s.B, pkh: s.pkh}
+	return &s.key
+}
+
+// expandSecretKey returns the validated secret key expanded into s, without A
+func (s *Scratch) expandSecretKey(sk *EncapsSecretKey) *expandedKey {
+
+	s.param.unpackEntries(s.B.data, sk.B)
+	for i := range sk.S {
+		copy(s.S.row(i), sk.S[i])
+	}
+	s.key = expandedKey{seedA: sk.SeedA, B: s.B, pkh: sk.Pkh, seedS: sk.SeedS, S: s.S}
+	return &s.key
+}
+
+// derive computes seedSE || k = SHAKE(pkh || m || salt) into s.seed
+// and samples S', E1 and E2 from seedSE
+func (s *Scratch) derive(pkh, m, salt []byte, S1, E1, E2 *matrix) {
+
+	param := s.param
+	s.seed = s.shake(s.seed[:0], param.lseedSE+param.lenk, pkh, m, salt)
+	s.seedSE = append(append(s.seedSE[:0], 0x96), s.seed[:param.lseedSE]...)
+	s.r = s.shake(s.r[:0], (2*param.no+param.n)*param.m*param.lenX, s.seedSE)
+
+	rLen := param.m * param.no * param.lenX
+	param.sampleEntries(S1.data, s.r[:rLen])
+	param.sampleEntries(E1.data, s.r[rLen:2*rLen])
+	param.sampleEntries(E2.data, s.r[2*rLen:])
+}
+
+// encaps appends the packed ciphertext c1 || c2 || salt to ctDst and ss to ssDst
+// using expanded public key and coins m || salt of EncapsulationSeedSize bytes
+func (s *Scratch) encaps(ctDst, ssDst []byte, key *expandedKey, coins []byte) (ct, ss []byte) {
+
+	param := s.param
+	m, salt := coins[:param.lenM], coins[param.lenM:]
+	s.derive(key.pkh, m, salt, s.S1, s.E1, s.E2)
+
+	param.mulAddSKeyTo(s.B1, s.S1, key, s.E1, s) // B' = S'*A + E1
+	mulAddABTo(s.V, s.S1, key.B, s.E2)           // V = S'*B + E2
+	param.encodeEntries(s.C.data, m)
+	addInto(s.C, s.C, s.V) // C = V + Encode(m)
+
+	start := len(ctDst)
+	ctDst = param.packEntries(ctDst, s.B1.data)
+	ctDst = param.packEntries(ctDst, s.C.data)
+	ctDst = append(ctDst, salt...)
+
+	ssDst = s.shake(ssDst, param.lenss, ctDst[start:], s.seed[param.lseedSE:]) // ss = SHAKE(c1 || c2 || salt || k)
+	return ctDst, ssDst
+}
+
+// decaps appends the secret ss of validated ciphertext c1 || c2 || salt to ssDst
+// using expanded secret key
+func (s *Scratch) decaps(ssDst []byte, key *expandedKey, c1, c2, salt []byte) []byte {
+
+	param := s.param
+	param.unpackEntries(s.Bp.data, c1)
+	param.unpackEntries(s.C.data, c2)
+
+	mulAddABTo(s.V, s.Bp, key.S, nil)
+	subInto(s.V, s.C, s.V) // M = C - B'*S
+	s.m = param.decodeEntries(s.m[:0], s.V.data)
+
+	s.derive(key.pkh, s.m, salt, s.S1, s.E1, s.E2)
+	param.reduce(param.mulAddSKeyTo(s.B2, s.S1, key, s.E1, s)) // B'' = S'*A + E1
+	mulAddABTo(s.V, s.S1, key.B, s.E2)                         // V = S'*B + E2
+	param.encodeEntries(s.C1.data, s.m)
+	param.reduce(addInto(s.C1, s.C1, s.V)) // C' = V + Encode(m')
+
+	// k' if (B', C) == (B'', C'), s otherwise, selected in constant time
+	s.k = append(s.k[:0], key.seedS...)
+	subtle.ConstantTimeCopy(ctEqual(s.Bp, s.B2)&ctEqual(s.C, s.C1), s.k, s.seed[param.lseedSE:])
+
+	return s.shake(ssDst, param.lenss, c1, c2, salt, s.k) // ss = SHAKE(c1 || c2 || salt || k)
+}
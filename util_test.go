@@ -0,0 +1,375 @@
+package frodo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testing constant-time dc & Sample against their textbook definitions
+// frodo pkg util.go, frodo.go
+
+func TestDc(t *testing.T) {
+
+	for _, param := range []*Parameters{Frodo640(), Frodo976(), Frodo1344()} {
+		b, d := float64(uint(1)<<uint(param.b)), float64(uint(1)<<uint(param.d-param.b))
+		for c := 0; c <= int(param.q); c++ {
+			expected := uint16(math.Mod(math.Floor(float64(c)/d+0.5), b))
+			if got := param.dc(uint16(c)); got != expected {
+				t.Fatal("util_test.go/TestDc: expected", expected, "but has got", got, "for", c)
+			}
+		}
+	}
+}
+
+func TestSampleCDT(t *testing.T) {
+
+	for _, param := range []*Parameters{Frodo640(), Frodo976(), Frodo1344()} {
+		for r := 0; r <= 0xffff; r++ {
+			e := uint16(0)
+			for z := 0; z < len(param.x)-1; z++ {
+				if uint16(r>>1) > param.x[z] {
+					e++
+				}
+			}
+			if r&1 != 0 {
+				e = (param.q + 1 - e) & param.q
+			}
+			if got := param.Sample(uint16(r)); got != e {
+				t.Fatal("util_test.go/TestSampleCDT: expected", e, "but has got", got, "for", r)
+			}
+		}
+	}
+}
+
+func TestSignExtend(t *testing.T) {
+
+	for _, param := range []*Parameters{Frodo640(), Frodo976()} {
+		for e := 0; e <= int(param.q); e++ {
+			expected := uint16(e)
+			if e > int(param.q>>1) {
+				expected |= ^param.q
+			}
+			if got := param.signExtend(uint16(e)); got != expected {
+				t.Fatal("util_test.go/TestSignExtend: expected", expected, "but has got", got, "for", e)
+			}
+		}
+	}
+}
+
+func TestCtEqual(t *testing.T) {
+
+	A, B := matrixOf([][]uint16{{1, 2}, {3, 4}}), matrixOf([][]uint16{{1, 2}, {3, 4}})
+	if ctEqual(A, B) != 1 {
+		t.Error("util_test.go/TestCtEqual: expected eq matrices")
+	}
+	B.data[3] = 0x8004
+	if ctEqual(A, B) != 0 {
+		t.Error("util_test.go/TestCtEqual: expected different matrices")
+	}
+}
+
+func TestMatrixKernels(t *testing.T) {
+
+	param := Frodo640()
+	rnd := rand.New(rand.NewSource(1))
+	random := func(n1, n2 int) *matrix {
+		M := newMatrix(n1, n2)
+		for i := range M.data {
+			M.data[i] = uint16(rnd.Intn(1 << 16))
+		}
+		return M
+	}
+	naive := func(A, B, E *matrix) *matrix { // (A*B + E) mod q entry by entry
+		C := newMatrix(A.rows, B.cols)
+		for i := 0; i < A.rows; i++ {
+			for j := 0; j < B.cols; j++ {
+				c := uint64(E.data[i*E.stride+j])
+				for k := 0; k < A.cols; k++ {
+					c += uint64(A.data[i*A.stride+k]) * uint64(B.data[k*B.stride+j])
+				}
+				C.data[i*C.stride+j] = uint16(c) & param.q
+			}
+		}
+		return C
+	}
+
+	A, B, E := random(8, 24), random(24, 16), random(8, 16)
+	want := naive(A, B, E)
+	if got := param.reduce(mulAddAB(A, B, E)); ctEqual(got, want) != 1 {
+		t.Error("util_test.go/TestMatrixKernels: mulAddAB differs from A*B + E")
+	}
+	if got := param.reduce(mulAddABt(A, B.transpose(), E)); ctEqual(got, want) != 1 {
+		t.Error("util_test.go/TestMatrixKernels: mulAddABt differs from A*B + E")
+	}
+	D := param.reduce(sub(add(A, A), A))
+	if ctEqual(D, param.reduce(A)) != 1 {
+		t.Error("util_test.go/TestMatrixKernels: A + A - A differs from A")
+	}
+	if ctEqual(matrixOf(A.slices()), A) != 1 || ctEqual(A.transpose().transpose(), A) != 1 {
+		t.Error("util_test.go/TestMatrixKernels: conversions change the matrix")
+	}
+}
+
+func TestStreamingA(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(2))
+	for _, param := range []*Parameters{Frodo640(), Frodo640AES(), Frodo640().Legacy()} {
+		seedA := make([]byte, param.lseedA)
+		rnd.Read(seedA)
+		S, E := newMatrix(param.m, param.no), newMatrix(param.m, param.no)
+		for i := range S.data {
+			S.data[i], E.data[i] = uint16(rnd.Intn(1<<16)), uint16(rnd.Intn(1<<16))
+		}
+		A := param.genMatrix(seedA)
+		if ctEqual(param.mulAddSA(S, seedA, E), mulAddAB(S, A, E)) != 1 {
+			t.Errorf("util_test.go/TestStreamingA: %s S*A + E differs from the materialized product", param.Name())
+		}
+		if ctEqual(param.mulAddAS(seedA, S, E.transpose()), mulAddABt(A, S, E.transpose())) != 1 {
+			t.Errorf("util_test.go/TestStreamingA: %s A*S + E differs from the materialized product", param.Name())
+		}
+	}
+}
+
+func TestShakeRowsOffsets(t *testing.T) {
+
+	param := Frodo976()
+	seedA := make([]byte, param.lseedA)
+	rand.New(rand.NewSource(3)).Read(seedA)
+	A := param.genMatrix(seedA)
+	r := newShakeRows(param, seedA)
+	for _, rows := range [][2]int{{0, 1}, {1, 3}, {3, 4}, {5, 7}, {250, 9}, {param.no - 5, 5}} {
+		dst := make([]uint16, rows[1]*param.no)
+		r.rows(dst, rows[0])
+		for k := 0; k < rows[1]; k++ {
+			for j, e := range A.row(rows[0] + k) {
+				if dst[k*param.no+j] != e {
+					t.Fatalf("util_test.go/TestShakeRowsOffsets: %d rows from %d differ from A at %d, %d", rows[1], rows[0], rows[0]+k, j)
+				}
+			}
+		}
+	}
+}
+
+func TestKernels(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(4))
+	for _, param := range []*Parameters{Frodo640(), Frodo976(), Frodo1344()} {
+		generic := *param
+		generic.kern = nil
+
+		b := make([]byte, 8*param.no*param.m) // two 2m-by-n samples
+		rnd.Read(b)
+		c, want := make([]uint16, param.no*param.n), make([]uint16, param.no*param.n)
+		param.kern.entries(c[:param.no], b)
+		generic.entries(want[:param.no], b)
+		if !equal16(c[:param.no], want[:param.no]) {
+			t.Errorf("util_test.go/TestKernels: %s entries differ from the generic code", param.Name())
+		}
+		param.sampleEntries(c, b)
+		generic.sampleEntries(want, b)
+		if !equal16(c, want) {
+			t.Errorf("util_test.go/TestKernels: %s sampleEntries differs from the generic code", param.Name())
+		}
+		if !bytes.Equal(param.packEntries(nil, c), generic.packEntries(nil, c)) {
+			t.Errorf("util_test.go/TestKernels: %s packEntries differs from the generic code", param.Name())
+		}
+		param.unpackEntries(c, b)
+		generic.unpackEntries(want, b)
+		if !equal16(c, want) {
+			t.Errorf("util_test.go/TestKernels: %s unpackEntries differs from the generic code", param.Name())
+		}
+
+		seedA := b[:param.lseedA]
+		S, E := param.sampleMatrix(b, 2*param.m, param.no), param.sampleMatrix(b[len(b)/2:], 2*param.m, param.no)
+		if ctEqual(param.mulAddSA(S, seedA, E), generic.mulAddSA(S, seedA, E)) != 1 {
+			t.Errorf("util_test.go/TestKernels: %s S*A + E differs from the generic code", param.Name())
+		}
+		S, E = S.slice(0, param.n), E.slice(0, param.n).transpose()
+		if ctEqual(param.mulAddAS(seedA, S, E), generic.mulAddAS(seedA, S, E)) != 1 {
+			t.Errorf("util_test.go/TestKernels: %s A*S + E differs from the generic code", param.Name())
+		}
+	}
+}
+
+func equal16(a, b []uint16) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// bit-level references of the word-level entry conversions of frodo.go
+
+// encodeEntriesGeneric encodes the B·len(c) bits of k into the entries c, bits are little-endian
+func (param *Parameters) encodeEntriesGeneric(c []uint16, k []byte) {
+
+	for i := range c {
+		temp := uint16(0)
+		for l := 0; l < param.b; l++ {
+			index, shift := (i*param.b+l)/8, uint((i*param.b+l)&7)
+			temp |= uint16((k[index]>>shift)&1) << uint(l) // little-endian
+		}
+		c[i] = param.ec(temp)
+	}
+}
+
+// decodeEntriesGeneric appends the B·len(c) bits decoded from the entries c to dst, bits are little-endian
+func (param *Parameters) decodeEntriesGeneric(dst []byte, c []uint16) []byte {
+
+	k := grow(&dst, param.b*len(c)/8)
+	for i := range c {
+		temp := param.dc(c[i])
+		for l := 0; l < param.b; l++ {
+			index, shift := (i*param.b+l)/8, uint((i*param.b+l)&7)
+			k[index] |= byte((temp>>uint(l))&1) << shift // little-endian
+		}
+	}
+	return dst
+}
+
+// packEntriesGeneric appends the low D bits of the entries c to dst, most significant bit first
+func (param *Parameters) packEntriesGeneric(dst []byte, c []uint16) []byte {
+
+	b := grow(&dst, param.d*len(c)/8)
+	for i := range c {
+		for l := 0; l < param.d; l++ {
+			index, shift := (i*param.d+l)/8, uint((i*param.d+l)&7)
+			b[index] |= byte((c[i]>>uint(param.d-1-l))&1) << (7 - shift)
+		}
+	}
+	return dst
+}
+
+// unpackEntriesGeneric unpacks the D·len(c) bits of b into the entries c
+func (param *Parameters) unpackEntriesGeneric(c []uint16, b []byte) {
+
+	for i := range c {
+		c[i] = 0
+		for l := 0; l < param.d; l++ {
+			index, shift := (i*param.d+l)/8, uint((i*param.d+l)&7)
+			c[i] |= uint16((b[index]>>(7-shift))&1) << uint(param.d-1-l)
+		}
+	}
+}
+
+// sampleEntriesGeneric samples the entries e from the 16-bit little-endian words of r
+func (param *Parameters) sampleEntriesGeneric(e []uint16, r []byte) {
+
+	for i := range e {
+		e[i] = param.Sample(uint16(r[2*i]) | (uint16(r[2*i+1]) << 8)) // little-endian
+	}
+}
+
+func TestEntriesFastPaths(t *testing.T) {
+
+	rnd := rand.New(rand.NewSource(4))
+	for _, param := range []*Parameters{Frodo640(), Frodo976(), Frodo1344()} {
+		for trial := 0; trial < 20; trial++ {
+			n := 8 * (1 + rnd.Intn(64))
+			c, r := make([]uint16, n), make([]byte, 2*n)
+			for i := range c {
+				c[i] = uint16(rnd.Intn(1 << 16)) // unreduced, only the low D bits are packed
+			}
+			rnd.Read(r)
+
+			packed := param.packEntries([]byte{1, 2}, c)
+			if !bytes.Equal(packed, param.packEntriesGeneric([]byte{1, 2}, c)) {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s packEntries differs from the reference", param.Name())
+			}
+			u, ug := make([]uint16, n), make([]uint16, n)
+			param.unpackEntries(u, packed[2:])
+			param.unpackEntriesGeneric(ug, packed[2:])
+			if ctEqual(matrixOf([][]uint16{u}), matrixOf([][]uint16{ug})) != 1 {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s unpackEntries differs from the reference", param.Name())
+			}
+
+			k := r[:param.b*n/8]
+			param.encodeEntries(u, k)
+			param.encodeEntriesGeneric(ug, k)
+			if ctEqual(matrixOf([][]uint16{u}), matrixOf([][]uint16{ug})) != 1 {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s encodeEntries differs from the reference", param.Name())
+			}
+			if !bytes.Equal(param.decodeEntries([]byte{3}, c), param.decodeEntriesGeneric([]byte{3}, c)) {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s decodeEntries differs from the reference", param.Name())
+			}
+
+			param.sampleEntries(u, r)
+			param.sampleEntriesGeneric(ug, r)
+			if ctEqual(matrixOf([][]uint16{u}), matrixOf([][]uint16{ug})) != 1 {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s sampleEntries differs from Sample", param.Name())
+			}
+		}
+
+		all, e := make([]byte, 2<<16), make([]uint16, 1<<16) // every 16-bit word
+		for w := 0; w < 1<<16; w++ {
+			all[2*w], all[2*w+1] = byte(w), byte(w>>8)
+		}
+		param.sampleEntries(e, all)
+		for w := range e {
+			if e[w] != param.Sample(uint16(w)) {
+				t.Fatalf("util_test.go/TestEntriesFastPaths: %s sampleEntries(%d) = %d, Sample = %d", param.Name(), w, e[w], param.Sample(uint16(w)))
+			}
+		}
+	}
+}
+
+// failingReader reads n zero bytes, then fails with err
+type failingReader struct {
+	n   int
+	err error
+}
+
+func (r *failingReader) Read(b []byte) (int, error) {
+
+	if r.n == 0 {
+		return 0, r.err
+	}
+	if len(b) > r.n {
+		b = b[:r.n]
+	}
+	for i := range b {
+		b[i] = 0
+	}
+	r.n -= len(b)
+	return len(b), nil
+}
+
+func TestEncapsulatorRandomFailure(t *testing.T) {
+
+	param := FrodoKEM640SHAKE()
+	pk, sk := param.EncapsKeyGen()
+	random := &failingReader{n: 2 * param.EncapsulationSeedSize(), err: io.ErrUnexpectedEOF}
+	e, err := param.newEncapsulator(pk, 4, random)
+	if err != nil {
+		t.Fatal("util_test.go/TestEncapsulatorRandomFailure:", err)
+	}
+
+	// the encapsulations drawn before the failure may still be handed out
+	for i := 0; i < 3 && err == nil; i++ {
+		var ct *EncapsCipherText
+		var ss []byte
+		if ct, ss, err = e.Encaps(); err == nil && !bytes.Equal(param.Decaps(ct, sk), ss) {
+			t.Error("util_test.go/TestEncapsulatorRandomFailure: precomputed encapsulation does not decapsulate")
+		}
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("util_test.go/TestEncapsulatorRandomFailure: expected the error of the reader, got %v", err)
+	}
+	if _, _, err := e.Encaps(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("util_test.go/TestEncapsulatorRandomFailure: later Encaps must keep failing, got %v", err)
+	}
+	e.Close()
+	if _, _, err := e.Encaps(); !errors.Is(err, io.ErrUnexpectedEOF) || e.Len() != 0 {
+		t.Errorf("util_test.go/TestEncapsulatorRandomFailure: expected the error of the reader and an empty pool after Close, got %v", err)
+	}
+}